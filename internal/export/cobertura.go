@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"path"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+type coberturaCoverage struct {
+	XMLName         xml.Name          `xml:"coverage"`
+	LineRate        float64           `xml:"line-rate,attr"`
+	BranchRate      float64           `xml:"branch-rate,attr"`
+	LinesCovered    int               `xml:"lines-covered,attr"`
+	LinesValid      int               `xml:"lines-valid,attr"`
+	BranchesCovered int               `xml:"branches-covered,attr"`
+	BranchesValid   int               `xml:"branches-valid,attr"`
+	Complexity      float64           `xml:"complexity,attr"`
+	Version         string            `xml:"version,attr"`
+	Timestamp       int64             `xml:"timestamp,attr"` // always 0, for reproducible output
+	Sources         coberturaSources  `xml:"sources"`
+	Packages        coberturaPackages `xml:"packages"`
+}
+
+type coberturaSources struct {
+	Sources []string `xml:"source"`
+}
+
+type coberturaPackages struct {
+	Packages []coberturaPackage `xml:"package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    coberturaClasses `xml:"classes"`
+}
+
+type coberturaClasses struct {
+	Classes []coberturaClass `xml:"class"`
+}
+
+type coberturaClass struct {
+	Name       string           `xml:"name,attr"`
+	Filename   string           `xml:"filename,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Methods    coberturaMethods `xml:"methods"`
+	Lines      coberturaLines   `xml:"lines"`
+}
+
+type coberturaMethods struct {
+	Methods []coberturaMethod `xml:"method"`
+}
+
+type coberturaMethod struct {
+	Name       string         `xml:"name,attr"`
+	Signature  string         `xml:"signature,attr"`
+	LineRate   float64        `xml:"line-rate,attr"`
+	BranchRate float64        `xml:"branch-rate,attr"`
+	Complexity float64        `xml:"complexity,attr"`
+	Lines      coberturaLines `xml:"lines"`
+}
+
+type coberturaLines struct {
+	Lines []coberturaLine `xml:"line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// writeCobertura writes data as Cobertura XML, with a package for each
+// directory of the file tree holding files and a class for each file.
+// Filenames are relative to the source root, which is recorded as the only
+// source so that GitLab and Jenkins can match them with the repository.
+// Rates follow the line metric: partially covered lines have hits but do not
+// count as covered.
+func writeCobertura(w io.Writer, data *model.CoverageData, opts Options) error {
+	source := opts.SourceRoot
+	if source == "" {
+		source = "."
+	}
+
+	report := coberturaCoverage{
+		LineRate:     rate(data.Summary.CoveredLines, data.Summary.TotalLines),
+		LinesCovered: data.Summary.CoveredLines,
+		LinesValid:   data.Summary.TotalLines,
+		Version:      "go-better-html-coverage",
+		Sources:      coberturaSources{Sources: []string{source}},
+	}
+	if data.Tree != nil {
+		report.Packages.Packages = coberturaTree(data, data.Tree, "", nil)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// coberturaTree appends a package for dir, when it holds files, and for each
+// of its subdirectories holding files.
+func coberturaTree(data *model.CoverageData, dir *model.TreeNode, dirPath string, packages []coberturaPackage) []coberturaPackage {
+	pkg := coberturaPackage{Name: dirPath}
+	if pkg.Name == "" {
+		pkg.Name = "."
+	}
+	var covered, total int
+	for _, child := range dir.Children {
+		if child.Type == "file" && child.FileID != nil {
+			class, c, t := coberturaFile(data.Files[*child.FileID])
+			pkg.Classes.Classes = append(pkg.Classes.Classes, class)
+			covered += c
+			total += t
+		}
+	}
+	if len(pkg.Classes.Classes) > 0 {
+		pkg.LineRate = rate(covered, total)
+		packages = append(packages, pkg)
+	}
+
+	for _, child := range dir.Children {
+		if child.Type == "dir" {
+			packages = coberturaTree(data, child, path.Join(dirPath, child.Name), packages)
+		}
+	}
+	return packages
+}
+
+// coberturaFile returns the class of a file, with its covered and total
+// line counts.
+func coberturaFile(file model.FileData) (class coberturaClass, covered, total int) {
+	class = coberturaClass{Name: path.Base(file.Path), Filename: file.Path}
+
+	for i, c := range file.Coverage {
+		if c == 0 {
+			continue
+		}
+		total++
+		if c == 2 {
+			covered++
+		}
+		class.Lines.Lines = append(class.Lines.Lines, coberturaLine{Number: i + 1, Hits: lineHits(file, i)})
+	}
+	class.LineRate = rate(covered, total)
+
+	for _, fn := range file.Functions {
+		method := coberturaMethod{Name: fn.DisplayName()}
+		var fnCovered int
+		for _, line := range class.Lines.Lines {
+			if line.Number >= fn.StartLine && line.Number <= fn.EndLine {
+				method.Lines.Lines = append(method.Lines.Lines, line)
+				if file.Coverage[line.Number-1] == 2 {
+					fnCovered++
+				}
+			}
+		}
+		method.LineRate = rate(fnCovered, len(method.Lines.Lines))
+		class.Methods.Methods = append(class.Methods.Methods, method)
+	}
+	return class, covered, total
+}
+
+// rate returns covered/total rounded to 4 decimals, 0 when total is 0.
+func rate(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(covered)/float64(total)*10000) / 10000
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteCobertura(t *testing.T) {
+	data := testData()
+	id0, id1 := 0, 1
+	data.Tree = &model.TreeNode{Name: ".", Type: "dir", Children: []*model.TreeNode{
+		{Name: "internal", Type: "dir", Children: []*model.TreeNode{
+			{Name: "app", Type: "dir", Children: []*model.TreeNode{
+				{Name: "app.go", Type: "file", FileID: &id0},
+				{Name: "unused.go", Type: "file", FileID: &id1},
+			}},
+		}},
+	}}
+	data.Summary = model.Summary{TotalLines: 5, CoveredLines: 3}
+
+	var buf bytes.Buffer
+	if err := writeCobertura(&buf, data, Options{SourceRoot: "/src/app"}); err != nil {
+		t.Fatalf("writeCobertura failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<!DOCTYPE coverage") {
+		t.Error("missing Cobertura DOCTYPE")
+	}
+
+	var report coberturaCoverage
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse Cobertura XML: %v", err)
+	}
+	if report.Timestamp != 0 {
+		t.Errorf("expected a zero timestamp for reproducible output, got %d", report.Timestamp)
+	}
+	if report.LineRate != 0.6 || report.LinesCovered != 3 || report.LinesValid != 5 {
+		t.Errorf("unexpected totals: rate %v, %d/%d", report.LineRate, report.LinesCovered, report.LinesValid)
+	}
+	if len(report.Sources.Sources) != 1 || report.Sources.Sources[0] != "/src/app" {
+		t.Errorf("unexpected sources: %v", report.Sources.Sources)
+	}
+
+	packages := report.Packages.Packages
+	if len(packages) != 1 || packages[0].Name != "internal/app" {
+		t.Fatalf("expected a single internal/app package, got %+v", packages)
+	}
+	classes := packages[0].Classes.Classes
+	if len(classes) != 2 {
+		t.Fatalf("expected 2 classes, got %d", len(classes))
+	}
+
+	app := classes[0]
+	if app.Name != "app.go" || app.Filename != "internal/app/app.go" {
+		t.Errorf("unexpected class: %s %s", app.Name, app.Filename)
+	}
+	if app.LineRate != 0.75 {
+		t.Errorf("expected class line rate 0.75, got %v", app.LineRate)
+	}
+	wantLines := []coberturaLine{{3, 3}, {4, 3}, {5, 0}, {7, 3}}
+	if len(app.Lines.Lines) != len(wantLines) {
+		t.Fatalf("expected lines %v, got %v", wantLines, app.Lines.Lines)
+	}
+	for i, want := range wantLines {
+		if app.Lines.Lines[i] != want {
+			t.Errorf("line %d: expected %v, got %v", i, want, app.Lines.Lines[i])
+		}
+	}
+	// Method rates follow the line metric like the class ones, not the
+	// statements of the function (1 of 2 covered)
+	if len(app.Methods.Methods) != 1 || app.Methods.Methods[0].Name != "(*Server).Run" || app.Methods.Methods[0].LineRate != 0.75 {
+		t.Errorf("unexpected methods: %+v", app.Methods.Methods)
+	}
+
+	if packages[0].LineRate != 0.6 {
+		t.Errorf("expected package line rate 0.6, got %v", packages[0].LineRate)
+	}
+}
@@ -0,0 +1,137 @@
+// Package export writes coverage data in formats consumed by other tools,
+// such as editors, CI systems and code quality dashboards.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/chmouel/go-better-html-coverage/internal/badge"
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// Options configures the exports.
+type Options struct {
+	SourceRoot string // absolute path of the source root, for formats that record it
+	MaxRows    int    // maximum rows of the Markdown tables and lists, 0 for the default
+	ReportURL  string // URL of the HTML report, to link files and lines to it
+
+	MaxAnnotations int              // maximum GitHub annotations, 0 for the default
+	Thresholds     badge.Thresholds // SARIF levels by file coverage, zero for the badge defaults
+}
+
+// writers maps the name of each supported format to its writer.
+var writers = map[string]func(io.Writer, *model.CoverageData, Options) error{
+	"cobertura": writeCobertura,
+	"github":    writeGitHub,
+	"json":      writeJSON,
+	"lcov":      writeLCOV,
+	"markdown":  writeMarkdown,
+	"sarif":     writeSARIF,
+	"sonar":     writeSonar,
+}
+
+// Formats returns the names of the supported formats, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Supported reports whether format is a supported export format.
+func Supported(format string) bool {
+	_, ok := writers[format]
+	return ok
+}
+
+// Write exports data in the given format to outputPath.
+// If outputPath is "-", the export is written to stdout.
+func Write(data *model.CoverageData, format, outputPath string, opts Options) error {
+	writer, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+
+	var buf bytes.Buffer
+	if err := writer(&buf, data, opts); err != nil {
+		return fmt.Errorf("writing %s: %w", format, err)
+	}
+
+	if outputPath == "-" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("writing %s to stdout: %w", format, err)
+		}
+		return nil
+	}
+
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0o644); err != nil { //nolint:gosec // G306: export should be readable
+		return fmt.Errorf("writing %s file: %w", format, err)
+	}
+	return nil
+}
+
+// checkChangedCode checks that data knows the changed code, from -base or
+// -ref, for the formats reporting on changed code only.
+func checkChangedCode(data *model.CoverageData) error {
+	if !data.IsDiffMode && data.Patch == nil {
+		return fmt.Errorf("the changed code is unknown, use -ref or -base")
+	}
+	return nil
+}
+
+// inHunks reports whether a 1-based line is inside one of hunks.
+func inHunks(hunks []model.Hunk, line int) bool {
+	return overlapsHunks(hunks, line, line)
+}
+
+// overlapsHunks reports whether the 1-based lines start to end overlap one
+// of hunks.
+func overlapsHunks(hunks []model.Hunk, start, end int) bool {
+	for _, h := range hunks {
+		if h.Start <= end && start <= h.End {
+			return true
+		}
+	}
+	return false
+}
+
+// maskStates returns a copy of the line states with the lines that fail keep
+// set to 0, for lineRanges.
+func maskStates(states []int, keep func(i int) bool) []int {
+	masked := make([]int, len(states))
+	for i, s := range states {
+		if keep(i) {
+			masked[i] = s
+		}
+	}
+	return masked
+}
+
+// lineHits returns the execution count of a line, from the counts of its
+// blocks when known.
+func lineHits(file model.FileData, i int) int {
+	if i < len(file.Counts) {
+		return file.Counts[i]
+	}
+	if file.Coverage[i] == 1 {
+		return 0
+	}
+	return 1
+}
+
+// functionCalls returns the number of times a function ran, which is the
+// count of its first block.
+func functionCalls(file model.FileData, fn model.Function) int {
+	for _, b := range file.Blocks {
+		if b.StartLine >= fn.StartLine && b.StartLine <= fn.EndLine {
+			return b.Count
+		}
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"github.com/chmouel/go-better-html-coverage/internal/parser"
+)
+
+// defaultMaxAnnotations is the number of annotations written when
+// Options.MaxAnnotations is not set. GitHub only shows 10 warnings per step.
+const defaultMaxAnnotations = 10
+
+// githubAnnotation is a range of lines to annotate.
+type githubAnnotation struct {
+	path       string
+	start, end int
+	title      string
+	message    string
+}
+
+// writeGitHub writes GitHub Actions workflow commands annotating the lines
+// to look at in a pull request: in diff mode the lines that lost coverage and
+// the new code that is not fully covered, otherwise the lines changed by -ref
+// that are not fully covered. Adjacent lines are grouped in a single
+// annotation and the annotations past Options.MaxAnnotations are summed up in
+// a notice.
+func writeGitHub(w io.Writer, data *model.CoverageData, opts Options) error {
+	if err := checkChangedCode(data); err != nil {
+		return err
+	}
+	maxAnnotations := opts.MaxAnnotations
+	if maxAnnotations <= 0 {
+		maxAnnotations = defaultMaxAnnotations
+	}
+
+	var annotations []githubAnnotation
+	for _, f := range data.Files {
+		add := func(states []int, state int, title, message string) {
+			for _, r := range lineRanges(states, state) {
+				annotations = append(annotations, githubAnnotation{
+					path:    githubPath(opts.SourceRoot, f.Path),
+					start:   r[0],
+					end:     r[1],
+					title:   title,
+					message: message,
+				})
+			}
+		}
+		if data.IsDiffMode {
+			add(f.DiffState, parser.DiffStateNewlyUncovered, "Coverage regression", "covered in the base profile, not covered anymore")
+			add(f.DiffState, parser.DiffStateNewlyPartial, "Coverage reduced", "covered in the base profile, only partially covered now")
+			newCode := maskStates(f.DiffState, func(i int) bool { return f.Coverage[i] == 1 })
+			add(newCode, parser.DiffStateNewCodeUncovered, "Not covered", "of new code not covered by tests")
+			newCode = maskStates(f.DiffState, func(i int) bool { return f.Coverage[i] == 3 })
+			add(newCode, parser.DiffStateNewCodeUncovered, "Partially covered", "of new code only partially covered by tests")
+		} else {
+			changed := maskStates(f.Coverage, func(i int) bool { return inHunks(f.Hunks, i+1) })
+			add(changed, 1, "Not covered", "not covered by tests")
+			add(changed, 3, "Partially covered", "only partially covered by tests")
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, a := range annotations {
+		if i == maxAnnotations {
+			fmt.Fprintf(bw, "::notice title=Coverage::%d more ranges not annotated\n", len(annotations)-maxAnnotations)
+			break
+		}
+		lines := fmt.Sprintf("Line %d", a.start)
+		if a.end > a.start {
+			lines = fmt.Sprintf("Lines %d-%d", a.start, a.end)
+		}
+		fmt.Fprintf(bw, "::warning file=%s,line=%d,endLine=%d,title=%s::%s\n",
+			githubProperty(a.path), a.start, a.end, githubProperty(a.title), githubData(lines+" "+a.message))
+	}
+	return bw.Flush()
+}
+
+// githubPath returns the path of a file relative to GITHUB_WORKSPACE, which
+// annotations are resolved against, when the source root is inside it.
+func githubPath(sourceRoot, filePath string) string {
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" || sourceRoot == "" {
+		return filePath
+	}
+	rel, err := filepath.Rel(workspace, filepath.Join(sourceRoot, filepath.FromSlash(filePath)))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filePath
+	}
+	return filepath.ToSlash(rel)
+}
+
+// githubData escapes the message of a workflow command.
+func githubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubProperty escapes a property value of a workflow command.
+func githubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	tests := []struct {
+		name      string
+		diff      bool
+		partial   bool // line 7 of app.go partially covered
+		max       int
+		workspace string
+		want      string
+	}{
+		{
+			name: "uncovered lines",
+			want: "::warning file=internal/app/app.go,line=5,endLine=5,title=Not covered::Line 5 not covered by tests\n" +
+				"::warning file=internal/app/unused.go,line=4,endLine=4,title=Not covered::Line 4 not covered by tests\n",
+		},
+		{
+			name:    "partially covered lines",
+			partial: true,
+			want: "::warning file=internal/app/app.go,line=5,endLine=5,title=Not covered::Line 5 not covered by tests\n" +
+				"::warning file=internal/app/app.go,line=7,endLine=7,title=Partially covered::Line 7 only partially covered by tests\n" +
+				"::warning file=internal/app/unused.go,line=4,endLine=4,title=Not covered::Line 4 not covered by tests\n",
+		},
+		{
+			name: "regressions",
+			diff: true,
+			want: "::warning file=internal/app/app.go,line=4,endLine=5,title=Coverage regression::Lines 4-5 covered in the base profile, not covered anymore\n" +
+				"::warning file=internal/app/app.go,line=3,endLine=3,title=Coverage reduced::Line 3 covered in the base profile, only partially covered now\n" +
+				"::warning file=internal/app/app.go,line=7,endLine=7,title=Not covered::Line 7 of new code not covered by tests\n",
+		},
+		{
+			name:    "partially covered new code",
+			diff:    true,
+			partial: true,
+			want: "::warning file=internal/app/app.go,line=4,endLine=5,title=Coverage regression::Lines 4-5 covered in the base profile, not covered anymore\n" +
+				"::warning file=internal/app/app.go,line=3,endLine=3,title=Coverage reduced::Line 3 covered in the base profile, only partially covered now\n" +
+				"::warning file=internal/app/app.go,line=7,endLine=7,title=Partially covered::Line 7 of new code only partially covered by tests\n",
+		},
+		{
+			name: "capped",
+			max:  1,
+			want: "::warning file=internal/app/app.go,line=5,endLine=5,title=Not covered::Line 5 not covered by tests\n" +
+				"::notice title=Coverage::1 more ranges not annotated\n",
+		},
+		{
+			name:      "relative to the workspace",
+			max:       1,
+			workspace: "/work",
+			want: "::warning file=sub/internal/app/app.go,line=5,endLine=5,title=Not covered::Line 5 not covered by tests\n" +
+				"::notice title=Coverage::1 more ranges not annotated\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_WORKSPACE", tt.workspace)
+			data := testData()
+			if tt.diff {
+				data.IsDiffMode = true
+				data.Files[0].Coverage = []int{0, 0, 3, 1, 1, 0, 1}
+				data.Files[0].DiffState = []int{0, 0, 7, 2, 2, 0, 6}
+				data.Files[1].DiffState = []int{0, 0, 0, 5, 0}
+			} else {
+				// Line 3 of app.go is uncovered, but outside the changed lines
+				data.Patch = &model.PatchSummary{}
+				data.Files[0].Coverage[2] = 1
+				data.Files[0].Hunks = []model.Hunk{{Start: 4, End: 7}}
+				data.Files[1].Hunks = []model.Hunk{{Start: 1, End: 5}}
+			}
+			if tt.partial {
+				data.Files[0].Coverage[6] = 3
+			}
+
+			var buf bytes.Buffer
+			if err := writeGitHub(&buf, data, Options{SourceRoot: "/work/sub", MaxAnnotations: tt.max}); err != nil {
+				t.Fatalf("writeGitHub failed: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("unexpected annotations:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteGitHubWithoutChanges(t *testing.T) {
+	err := writeGitHub(&bytes.Buffer{}, testData(), Options{})
+	if err == nil || !strings.Contains(err.Error(), "-ref or -base") {
+		t.Errorf("expected an error about -ref or -base, got %v", err)
+	}
+}
+
+func TestGitHubProperty(t *testing.T) {
+	if got := githubProperty("a,b:c%d\n"); got != "a%2Cb%3Ac%25d%0A" {
+		t.Errorf("unexpected escaping: %q", got)
+	}
+}
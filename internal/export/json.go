@@ -0,0 +1,17 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// writeJSON writes data as a versioned model.Report, sources included, which
+// can be read back to render any other format later.
+func writeJSON(w io.Writer, data *model.CoverageData, _ Options) error {
+	return json.NewEncoder(w).Encode(model.Report{
+		SchemaVersion: model.SchemaVersion,
+		Coverage:      data,
+	})
+}
@@ -0,0 +1,30 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, testData(), Options{}); err != nil {
+		t.Fatalf("writeJSON failed: %v", err)
+	}
+
+	var report model.Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if report.SchemaVersion != model.SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", model.SchemaVersion, report.SchemaVersion)
+	}
+	if report.Coverage == nil || len(report.Coverage.Files) != 2 {
+		t.Fatalf("unexpected coverage: %+v", report.Coverage)
+	}
+	if got := report.Coverage.Files[0].Lines[2]; got != "func (s *Server) Run() {" {
+		t.Errorf("expected sources to be embedded, got %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// writeLCOV writes data as an LCOV tracefile: one record per file with its
+// functions, the hit count of every line holding statements, and the line
+// and function totals. Go profiles have no branch data, so no branch records
+// are written. Partially covered lines count as hit, as some of their code
+// ran.
+func writeLCOV(w io.Writer, data *model.CoverageData, _ Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "TN:")
+	for _, file := range data.Files {
+		fmt.Fprintf(bw, "SF:%s\n", file.Path)
+
+		functionsHit := 0
+		for _, fn := range file.Functions {
+			fmt.Fprintf(bw, "FN:%d,%s\n", fn.StartLine, fn.DisplayName())
+		}
+		for _, fn := range file.Functions {
+			calls := functionCalls(file, fn)
+			if calls > 0 {
+				functionsHit++
+			}
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", calls, fn.DisplayName())
+		}
+		fmt.Fprintf(bw, "FNF:%d\n", len(file.Functions))
+		fmt.Fprintf(bw, "FNH:%d\n", functionsHit)
+
+		linesFound, linesHit := 0, 0
+		for i, c := range file.Coverage {
+			if c == 0 {
+				continue
+			}
+			hits := lineHits(file, i)
+			linesFound++
+			if hits > 0 {
+				linesHit++
+			}
+			fmt.Fprintf(bw, "DA:%d,%d\n", i+1, hits)
+		}
+		fmt.Fprintf(bw, "LF:%d\n", linesFound)
+		fmt.Fprintf(bw, "LH:%d\n", linesHit)
+		fmt.Fprintln(bw, "end_of_record")
+	}
+	return bw.Flush()
+}
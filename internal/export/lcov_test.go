@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func testData() *model.CoverageData {
+	return &model.CoverageData{
+		Mode: "count",
+		Files: []model.FileData{
+			{
+				ID:   0,
+				Path: "internal/app/app.go",
+				Lines: []string{
+					"package app",
+					"",
+					"func (s *Server) Run() {",
+					"\tif s.debug {",
+					"\t\tprintln(\"debug\")",
+					"\t}",
+					"}",
+				},
+				Coverage: []int{0, 0, 2, 2, 1, 0, 2},
+				Counts:   []int{0, 0, 3, 3, 0, 0, 3},
+				Blocks: []model.Block{
+					{StartLine: 3, StartCol: 24, EndLine: 4, EndCol: 13, NumStmt: 1, Count: 3},
+					{StartLine: 4, StartCol: 13, EndLine: 6, EndCol: 3, NumStmt: 1, Count: 0},
+				},
+				Functions: []model.Function{
+					{Name: "Run", Receiver: "*Server", StartLine: 3, EndLine: 7, Statements: 2, Covered: 1, Percent: 50},
+				},
+			},
+			{
+				ID:        1,
+				Path:      "internal/app/unused.go",
+				Lines:     []string{"package app", "", "func unused() {", "\tprintln()", "}"},
+				Coverage:  []int{0, 0, 0, 1, 0},
+				Counts:    []int{0, 0, 0, 0, 0},
+				Blocks:    []model.Block{{StartLine: 3, StartCol: 15, EndLine: 5, EndCol: 2, NumStmt: 1}},
+				Functions: []model.Function{{Name: "unused", StartLine: 3, EndLine: 5, Statements: 1}},
+			},
+		},
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeLCOV(&buf, testData(), Options{}); err != nil {
+		t.Fatalf("writeLCOV failed: %v", err)
+	}
+
+	want := `TN:
+SF:internal/app/app.go
+FN:3,(*Server).Run
+FNDA:3,(*Server).Run
+FNF:1
+FNH:1
+DA:3,3
+DA:4,3
+DA:5,0
+DA:7,3
+LF:4
+LH:3
+end_of_record
+SF:internal/app/unused.go
+FN:3,unused
+FNDA:0,unused
+FNF:1
+FNH:0
+DA:4,0
+LF:1
+LH:0
+end_of_record
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected LCOV output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "coverage.info")
+	if err := Write(testData(), "lcov", outputPath, Options{}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	//nolint:gosec // G304: outputPath is from t.TempDir
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("TN:\nSF:internal/app/app.go\n")) {
+		t.Errorf("unexpected export content:\n%s", content)
+	}
+
+	if err := Write(testData(), "unknown", outputPath, Options{}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if Supported("unknown") || !Supported("lcov") {
+		t.Error("unexpected Supported result")
+	}
+}
@@ -0,0 +1,224 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"github.com/chmouel/go-better-html-coverage/internal/parser"
+)
+
+// defaultMaxRows is the number of rows of the Markdown tables and lists when
+// Options.MaxRows is not set.
+const defaultMaxRows = 10
+
+// markdownPackage is a row of the Markdown package table.
+type markdownPackage struct {
+	name   string
+	totals model.Totals
+	base   model.Totals
+	inBase bool // whether any file of the package is in the base
+}
+
+// writeMarkdown writes a summary of data for pull request comments and
+// GitHub step summaries: the overall and patch coverage, a table of packages,
+// the least covered files and, in diff mode, the lines that lost coverage.
+// Files and lines link to the HTML report when Options.ReportURL is set.
+func writeMarkdown(w io.Writer, data *model.CoverageData, opts Options) error {
+	maxRows := opts.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultMaxRows
+	}
+	metric := data.Summary.Metric
+	if metric == "" {
+		metric = model.MetricLines
+	}
+	unit := "Lines"
+	if metric == model.MetricStatements {
+		unit = "Statements"
+	}
+	diff := data.IsDiffMode && data.DiffSummary != nil
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "## Coverage report")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "**%.1f%%** of %s covered", data.Summary.Percent, metric)
+	if diff {
+		fmt.Fprintf(bw, " (%+.1f%% from %.1f%%)", data.DiffSummary.DeltaPercent, data.DiffSummary.BasePercent)
+	}
+	fmt.Fprintf(bw, ": %d/%d lines, %d/%d statements",
+		data.Summary.CoveredLines, data.Summary.TotalLines,
+		data.Summary.CoveredStatements, data.Summary.TotalStatements)
+	if data.Summary.PartialLines > 0 {
+		fmt.Fprintf(bw, ", %d partially covered lines", data.Summary.PartialLines)
+	}
+	fmt.Fprintln(bw)
+	if diff {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "+%d newly covered lines, -%d regressions; new code: %d covered, %d uncovered lines\n",
+			data.DiffSummary.NewlyCoveredLines, data.DiffSummary.NewlyUncoveredLines,
+			data.DiffSummary.NewCodeCoveredLines, data.DiffSummary.NewCodeUncoveredLines)
+	}
+	if data.Patch != nil {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "Patch coverage: **%.1f%%** of changed lines: %d/%d lines\n",
+			data.Patch.Percent, data.Patch.CoveredLines, data.Patch.TotalLines)
+	}
+
+	// Packages
+	packages := markdownPackages(data.Files)
+	fmt.Fprintln(bw)
+	header, align := "| Package | "+unit+" | Covered | % |", "| :-- | --: | --: | --: |"
+	if diff {
+		header, align = header+" Δ |", align+" --: |"
+	}
+	fmt.Fprintln(bw, header)
+	fmt.Fprintln(bw, align)
+	for i, pkg := range packages {
+		if i == maxRows {
+			break
+		}
+		total, covered := pkg.totals.TotalLines, pkg.totals.CoveredLines
+		if metric == model.MetricStatements {
+			total, covered = pkg.totals.TotalStatements, pkg.totals.CoveredStatements
+		}
+		fmt.Fprintf(bw, "| `%s` | %d | %d | %.1f%% |", pkg.name, total, covered, pkg.totals.Percent(metric))
+		switch {
+		case diff && !pkg.inBase:
+			fmt.Fprint(bw, " new |")
+		case diff:
+			fmt.Fprintf(bw, " %+.1f%% |", pkg.totals.Percent(metric)-pkg.base.Percent(metric))
+		}
+		fmt.Fprintln(bw)
+	}
+	if len(packages) > maxRows {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "_%d more packages not shown._\n", len(packages)-maxRows)
+	}
+
+	// Least covered files
+	files := make([]model.FileData, 0, len(data.Files))
+	for _, f := range data.Files {
+		if f.Totals().TotalLines > 0 {
+			files = append(files, f)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Totals().Percent(metric) < files[j].Totals().Percent(metric)
+	})
+	if len(files) > maxRows {
+		files = files[:maxRows]
+	}
+	if len(files) > 0 {
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "<details>")
+		fmt.Fprintf(bw, "<summary>%d least covered files</summary>\n", len(files))
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "| File | % |")
+		fmt.Fprintln(bw, "| :-- | --: |")
+		for _, f := range files {
+			fmt.Fprintf(bw, "| %s | %.1f%% |\n", markdownLink(opts.ReportURL, f.Path, f.ID, 0, 0), f.Totals().Percent(metric))
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "</details>")
+	}
+
+	// Regressions
+	if diff && data.DiffSummary.NewlyUncoveredLines > 0 {
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "<details>")
+		fmt.Fprintf(bw, "<summary>%d lines lost coverage</summary>\n", data.DiffSummary.NewlyUncoveredLines)
+		fmt.Fprintln(bw)
+		shown := 0
+		for _, f := range data.Files {
+			for _, r := range lineRanges(f.DiffState, parser.DiffStateNewlyUncovered) {
+				if shown == maxRows {
+					break
+				}
+				fmt.Fprintf(bw, "- %s\n", markdownLink(opts.ReportURL, f.Path, f.ID, r[0], r[1]))
+				shown++
+			}
+		}
+		if ranges := countRanges(data.Files); ranges > shown {
+			fmt.Fprintf(bw, "- _%d more ranges not shown_\n", ranges-shown)
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "</details>")
+	}
+	return bw.Flush()
+}
+
+// markdownPackages groups files by directory, sorted by path.
+func markdownPackages(files []model.FileData) []markdownPackage {
+	byName := make(map[string]*markdownPackage)
+	var names []string
+	for _, f := range files {
+		name := path.Dir(f.Path)
+		pkg, ok := byName[name]
+		if !ok {
+			pkg = &markdownPackage{name: name}
+			byName[name] = pkg
+			names = append(names, name)
+		}
+		pkg.totals.Add(f.Totals())
+		if f.Base != nil {
+			pkg.base.Add(*f.Base)
+			pkg.inBase = true
+		}
+	}
+	sort.Strings(names)
+
+	packages := make([]markdownPackage, 0, len(names))
+	for _, name := range names {
+		packages = append(packages, *byName[name])
+	}
+	return packages
+}
+
+// markdownLink renders a file reference, with a line range when start is
+// set, linked to the HTML report when reportURL is set.
+func markdownLink(reportURL, filePath string, fileID, start, end int) string {
+	text := filePath
+	anchor := fmt.Sprintf("#file-%d", fileID)
+	switch {
+	case start > 0 && end > start:
+		text += fmt.Sprintf(":%d-%d", start, end)
+		anchor += fmt.Sprintf(":line-%d-%d", start, end)
+	case start > 0:
+		text += fmt.Sprintf(":%d", start)
+		anchor += fmt.Sprintf(":line-%d", start)
+	}
+	if reportURL == "" {
+		return "`" + text + "`"
+	}
+	return "[`" + text + "`](" + reportURL + anchor + ")"
+}
+
+// lineRanges returns the ranges of consecutive lines, 1-based and
+// inclusive, whose state is state.
+func lineRanges(states []int, state int) [][2]int {
+	var ranges [][2]int
+	for i, s := range states {
+		if s != state {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1][1] == i {
+			ranges[n-1][1] = i + 1
+			continue
+		}
+		ranges = append(ranges, [2]int{i + 1, i + 1})
+	}
+	return ranges
+}
+
+// countRanges returns the number of regression ranges over files.
+func countRanges(files []model.FileData) int {
+	n := 0
+	for _, f := range files {
+		n += len(lineRanges(f.DiffState, parser.DiffStateNewlyUncovered))
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	data := testData()
+	data.Summary = model.Summary{TotalLines: 5, CoveredLines: 3, TotalStatements: 3, CoveredStatements: 1, Metric: model.MetricLines, Percent: 60}
+
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, data, Options{}); err != nil {
+		t.Fatalf("writeMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"**60.0%** of lines covered: 3/5 lines, 1/3 statements",
+		"| Package | Lines | Covered | % |\n| :-- | --: | --: | --: |\n",
+		"| `internal/app` | 5 | 3 | 60.0% |",
+		"<summary>2 least covered files</summary>",
+		"| `internal/app/unused.go` | 0.0% |\n| `internal/app/app.go` | 75.0% |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Δ") || strings.Contains(out, "lost coverage") {
+		t.Errorf("diff columns should only be shown in diff mode:\n%s", out)
+	}
+}
+
+func TestWriteMarkdownDiff(t *testing.T) {
+	data := testData()
+	data.Summary = model.Summary{TotalLines: 5, CoveredLines: 3, Metric: model.MetricLines, Percent: 60}
+	data.IsDiffMode = true
+	data.DiffSummary = &model.DiffSummary{NewlyCoveredLines: 1, NewlyUncoveredLines: 3, NewCodeCoveredLines: 2, NewCodeUncoveredLines: 1, DeltaPercent: -20, BasePercent: 80}
+	data.Files[0].Base = &model.Totals{TotalLines: 4, CoveredLines: 4}
+	data.Files[0].DiffState = []int{0, 0, 3, 3, 2, 0, 1}
+	data.Files[1].DiffState = []int{0, 0, 0, 2, 0}
+
+	var buf bytes.Buffer
+	opts := Options{MaxRows: 1, ReportURL: "https://example.com/coverage.html"}
+	if err := writeMarkdown(&buf, data, opts); err != nil {
+		t.Fatalf("writeMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"**60.0%** of lines covered (-20.0% from 80.0%)",
+		"+1 newly covered lines, -3 regressions; new code: 2 covered, 1 uncovered lines",
+		"| `internal/app` | 5 | 3 | 60.0% | -40.0% |",
+		"<summary>1 least covered files</summary>",
+		"| [`internal/app/unused.go`](https://example.com/coverage.html#file-1) | 0.0% |",
+		"<summary>3 lines lost coverage</summary>",
+		"- [`internal/app/app.go:5`](https://example.com/coverage.html#file-0:line-5)\n- _1 more ranges not shown_",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownNewPackage(t *testing.T) {
+	data := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "app/app.go", Coverage: []int{2, 2}, Base: &model.Totals{TotalLines: 2, CoveredLines: 1}},
+			{Path: "web/web.go", Coverage: []int{2, 1}},
+		},
+		Summary:     model.Summary{Metric: model.MetricLines},
+		IsDiffMode:  true,
+		DiffSummary: &model.DiffSummary{},
+	}
+
+	var buf bytes.Buffer
+	if err := writeMarkdown(&buf, data, Options{}); err != nil {
+		t.Fatalf("writeMarkdown failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"| `app` | 2 | 2 | 100.0% | +50.0% |",
+		"| `web` | 2 | 1 | 50.0% | new |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	got := lineRanges([]int{2, 2, 0, 2, 1, 2, 2, 2}, 2)
+	want := [][2]int{{1, 2}, {4, 4}, {6, 8}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/badge"
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"github.com/chmouel/go-better-html-coverage/internal/parser"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifRootID  = "SRCROOT"
+)
+
+// SARIF rule IDs.
+const (
+	ruleCoverageRegression       = "coverage-regression"
+	ruleUncoveredNewCode         = "uncovered-new-code"
+	ruleUntestedExportedFunction = "untested-exported-function"
+)
+
+// sarifRules are the rules reported in the SARIF log, in the order of their
+// ruleIndex.
+var sarifRules = []sarifRule{
+	{
+		ID:               ruleCoverageRegression,
+		Name:             "CoverageRegression",
+		ShortDescription: sarifMessage{Text: "Lines covered in the base profile are not covered, or only partially, anymore."},
+	},
+	{
+		ID:               ruleUncoveredNewCode,
+		Name:             "UncoveredNewCode",
+		ShortDescription: sarifMessage{Text: "Lines added or edited since the base profile or by the -ref range are not fully covered."},
+	},
+	{
+		ID:               ruleUntestedExportedFunction,
+		Name:             "UntestedExportedFunction",
+		ShortDescription: sarifMessage{Text: "An exported function or method with changed lines never runs in the tests."},
+	},
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// writeSARIF writes a SARIF 2.1.0 log with a result for each range of lines
+// that lost coverage in diff mode, each range of new code that is not fully
+// covered, which is the code changed by -ref outside diff mode, and each
+// exported function with changed lines that never ran. The level of a result
+// follows the coverage of its file: error up to the red threshold of
+// Options.Thresholds, warning below the yellow one and note above.
+func writeSARIF(w io.Writer, data *model.CoverageData, opts Options) error {
+	if err := checkChangedCode(data); err != nil {
+		return err
+	}
+	thresholds := opts.Thresholds
+	if thresholds == (badge.Thresholds{}) {
+		thresholds = badge.DefaultThresholds()
+	}
+	metric := data.Summary.Metric
+	if metric == "" {
+		metric = model.MetricLines
+	}
+
+	results := []sarifResult{}
+	for _, f := range data.Files {
+		percent := f.Totals().Percent(metric)
+		level := sarifLevel(percent, thresholds)
+		result := func(rule, message string, start, end int) sarifResult {
+			return sarifResult{
+				RuleID:    rule,
+				RuleIndex: sarifRuleIndex(rule),
+				Level:     level,
+				Message:   sarifMessage{Text: fmt.Sprintf("%s (file coverage %.1f%%).", message, percent)},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLoc{URI: f.Path, URIBaseID: sarifRootID},
+					Region:           sarifRegion{StartLine: start, EndLine: end},
+				}}},
+			}
+		}
+
+		add := func(states []int, state int, rule, message string) {
+			for _, r := range lineRanges(states, state) {
+				results = append(results, result(rule, message, r[0], r[1]))
+			}
+		}
+		uncovered := func(i int) bool { return f.Coverage[i] == 1 }
+		partial := func(i int) bool { return f.Coverage[i] == 3 }
+		if data.IsDiffMode {
+			add(f.DiffState, parser.DiffStateNewlyUncovered, ruleCoverageRegression, "Covered in the base profile, not covered anymore")
+			add(f.DiffState, parser.DiffStateNewlyPartial, ruleCoverageRegression, "Covered in the base profile, only partially covered now")
+			add(maskStates(f.DiffState, uncovered), parser.DiffStateNewCodeUncovered, ruleUncoveredNewCode, "New code not covered by tests")
+			add(maskStates(f.DiffState, partial), parser.DiffStateNewCodeUncovered, ruleUncoveredNewCode, "New code only partially covered by tests")
+		} else {
+			changed := maskStates(f.Coverage, func(i int) bool { return inHunks(f.Hunks, i+1) })
+			add(changed, 1, ruleUncoveredNewCode, "Changed code not covered by tests")
+			add(changed, 3, ruleUncoveredNewCode, "Changed code only partially covered by tests")
+		}
+		for _, fn := range f.Functions {
+			if fn.Statements > 0 && fn.Covered == 0 && token.IsExported(fn.Name) && overlapsHunks(f.Hunks, fn.StartLine, fn.EndLine) {
+				results = append(results, result(ruleUntestedExportedFunction,
+					fmt.Sprintf("%s is never called by the tests", fn.DisplayName()), fn.StartLine, fn.EndLine))
+			}
+		}
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "go-better-html-coverage",
+			InformationURI: "https://github.com/chmouel/go-better-html-coverage",
+			Rules:          sarifRules,
+		}},
+		Results: results,
+	}
+	if opts.SourceRoot != "" && filepath.IsAbs(opts.SourceRoot) {
+		root := (&url.URL{Scheme: "file", Path: filepath.ToSlash(opts.SourceRoot)}).String()
+		if !strings.HasSuffix(root, "/") {
+			root += "/"
+		}
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{sarifRootID: {URI: root}}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+// sarifRuleIndex returns the index of a rule in sarifRules.
+func sarifRuleIndex(id string) int {
+	for i, r := range sarifRules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// sarifLevel returns the level of the results of a file with the given
+// coverage, with the same bounds as the badge colours.
+func sarifLevel(percent float64, thresholds badge.Thresholds) string {
+	switch {
+	case percent >= thresholds.Yellow:
+		return "note"
+	case percent > thresholds.Red:
+		return "warning"
+	default:
+		return "error"
+	}
+}
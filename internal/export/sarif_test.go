@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/badge"
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	data := testData()
+	data.IsDiffMode = true
+	data.Files[0].Base = &model.Totals{TotalLines: 4, CoveredLines: 4}
+	data.Files[0].DiffState = []int{0, 0, 3, 2, 2, 0, 3}
+	data.Files[1].DiffState = []int{0, 0, 0, 6, 0}
+	data.Files[1].Hunks = []model.Hunk{{Start: 1, End: 5}}
+	data.Files[1].Functions[0].Name = "Unused"
+
+	var buf bytes.Buffer
+	opts := Options{SourceRoot: "/work/src", Thresholds: badge.Thresholds{Red: 50, Yellow: 80}}
+	if err := writeSARIF(&buf, data, opts); err != nil {
+		t.Fatalf("writeSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+	if got := run.OriginalURIBaseIDs["SRCROOT"].URI; got != "file:///work/src/" {
+		t.Errorf("unexpected source root URI %q", got)
+	}
+
+	want := []struct {
+		rule       string
+		level      string
+		path       string
+		start, end int
+	}{
+		{ruleCoverageRegression, "warning", "internal/app/app.go", 4, 5},
+		{ruleUncoveredNewCode, "error", "internal/app/unused.go", 4, 4},
+		{ruleUntestedExportedFunction, "error", "internal/app/unused.go", 3, 5},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), run.Results)
+	}
+	for i, w := range want {
+		r := run.Results[i]
+		loc := r.Locations[0].PhysicalLocation
+		if r.RuleID != w.rule || r.Level != w.level || loc.ArtifactLocation.URI != w.path ||
+			loc.Region.StartLine != w.start || loc.Region.EndLine != w.end {
+			t.Errorf("result %d: expected %+v, got %+v", i, w, r)
+		}
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("result %d: ruleIndex %d does not match %s", i, r.RuleIndex, r.RuleID)
+		}
+	}
+}
+
+func TestWriteSARIFChangedCode(t *testing.T) {
+	data := testData()
+	data.Patch = &model.PatchSummary{}
+	data.Files[0].Coverage[6] = 3
+	data.Files[0].Hunks = []model.Hunk{{Start: 6, End: 7}}
+	data.Files[1].Functions[0].Name = "Unused" // exported but unchanged
+
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, data, Options{}); err != nil {
+		t.Fatalf("writeSARIF failed: %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	results := log.Runs[0].Results
+	if len(results) != 1 || results[0].RuleID != ruleUncoveredNewCode ||
+		results[0].Locations[0].PhysicalLocation.Region.StartLine != 7 ||
+		!strings.Contains(results[0].Message.Text, "only partially covered") {
+		t.Errorf("expected the partially covered changed line only, got %+v", results)
+	}
+}
+
+func TestWriteSARIFNoResults(t *testing.T) {
+	data := testData()
+	data.Patch = &model.PatchSummary{}
+
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, data, Options{}); err != nil {
+		t.Fatalf("writeSARIF failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"results": []`)) {
+		t.Errorf("expected an empty results array, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteSARIFWithoutChanges(t *testing.T) {
+	err := writeSARIF(&bytes.Buffer{}, testData(), Options{})
+	if err == nil || !strings.Contains(err.Error(), "-ref or -base") {
+		t.Errorf("expected an error about -ref or -base, got %v", err)
+	}
+}
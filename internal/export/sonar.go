@@ -0,0 +1,73 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+type sonarCoverage struct {
+	XMLName xml.Name    `xml:"coverage"`
+	Version int         `xml:"version,attr"`
+	Files   []sonarFile `xml:"file"`
+}
+
+type sonarFile struct {
+	Path  string      `xml:"path,attr"`
+	Lines []sonarLine `xml:"lineToCover"`
+}
+
+type sonarLine struct {
+	LineNumber      int  `xml:"lineNumber,attr"`
+	Covered         bool `xml:"covered,attr"`
+	BranchesToCover int  `xml:"branchesToCover,attr,omitempty"`
+	CoveredBranches int  `xml:"coveredBranches,attr,omitempty"`
+}
+
+// writeSonar writes data in the SonarQube generic test coverage format.
+// Partially covered lines are reported as covered with branch data, each
+// block with statements on the line counting as a branch.
+func writeSonar(w io.Writer, data *model.CoverageData, _ Options) error {
+	report := sonarCoverage{Version: 1}
+	for _, file := range data.Files {
+		sf := sonarFile{Path: file.Path}
+		for i, c := range file.Coverage {
+			if c == 0 {
+				continue
+			}
+			line := sonarLine{LineNumber: i + 1, Covered: c != 1}
+			if c == 3 {
+				line.BranchesToCover, line.CoveredBranches = lineBranches(file, i+1)
+			}
+			sf.Lines = append(sf.Lines, line)
+		}
+		report.Files = append(report.Files, sf)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// lineBranches returns the number of blocks with statements on a line, and
+// how many of them ran.
+func lineBranches(file model.FileData, lineNum int) (total, covered int) {
+	for _, b := range file.Blocks {
+		if b.NumStmt == 0 || lineNum < b.StartLine || lineNum > b.EndLine {
+			continue
+		}
+		total++
+		if b.Count > 0 {
+			covered++
+		}
+	}
+	return total, covered
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestWriteSonar(t *testing.T) {
+	data := testData()
+	// "if err != nil { return err }" with the return never run
+	data.Files = append(data.Files, model.FileData{
+		ID:       2,
+		Path:     "internal/app/partial.go",
+		Lines:    []string{"package app", "", "func check(err error) error {", "\tif err != nil { return err }", "\treturn nil", "}"},
+		Coverage: []int{0, 0, 2, 3, 2, 0},
+		Blocks: []model.Block{
+			{StartLine: 3, StartCol: 29, EndLine: 4, EndCol: 16, NumStmt: 1, Count: 1},
+			{StartLine: 4, StartCol: 16, EndLine: 4, EndCol: 29, NumStmt: 1, Count: 0},
+			{StartLine: 5, StartCol: 2, EndLine: 5, EndCol: 12, NumStmt: 1, Count: 1},
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := writeSonar(&buf, data, Options{}); err != nil {
+		t.Fatalf("writeSonar failed: %v", err)
+	}
+
+	var report sonarCoverage
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse Sonar XML: %v", err)
+	}
+	if report.Version != 1 || len(report.Files) != 3 {
+		t.Fatalf("unexpected report: version %d, %d files", report.Version, len(report.Files))
+	}
+
+	app := report.Files[0]
+	if app.Path != "internal/app/app.go" {
+		t.Errorf("expected path relative to the source root, got %s", app.Path)
+	}
+	wantApp := []sonarLine{
+		{LineNumber: 3, Covered: true},
+		{LineNumber: 4, Covered: true},
+		{LineNumber: 5, Covered: false},
+		{LineNumber: 7, Covered: true},
+	}
+	if len(app.Lines) != len(wantApp) {
+		t.Fatalf("expected %v, got %v", wantApp, app.Lines)
+	}
+	for i, want := range wantApp {
+		if app.Lines[i] != want {
+			t.Errorf("line %d: expected %+v, got %+v", i, want, app.Lines[i])
+		}
+	}
+
+	partial := report.Files[2].Lines[1]
+	want := sonarLine{LineNumber: 4, Covered: true, BranchesToCover: 2, CoveredBranches: 1}
+	if partial != want {
+		t.Errorf("expected partial line %+v, got %+v", want, partial)
+	}
+}
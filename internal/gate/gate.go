@@ -0,0 +1,88 @@
+// Package gate checks coverage data against the thresholds a build must meet.
+package gate
+
+import (
+	"fmt"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// Config holds the gates to check, nil when not set.
+type Config struct {
+	MinCoverage    *float64 // minimum total coverage of the selected metric
+	MaxRegressions *int     // maximum lines that lost coverage, diff mode only
+	MinDelta       *float64 // minimum coverage change from the base, diff mode only
+	Rules          []Rule   // minimum coverage of directories, from -thresholds
+
+	MinPatchCoverage *float64 // minimum coverage of the changed lines, -ref only
+}
+
+// Check evaluates the gates of cfg against data, in the order of Config.
+// The diff mode gates need data computed against a base profile.
+func Check(data *model.CoverageData, cfg Config) ([]model.GateResult, error) {
+	diff := data.IsDiffMode && data.DiffSummary != nil
+	if !diff && (cfg.MaxRegressions != nil || cfg.MinDelta != nil) {
+		return nil, fmt.Errorf("-max-regressions and -min-delta need a -base profile")
+	}
+	if data.Patch == nil && cfg.MinPatchCoverage != nil {
+		return nil, fmt.Errorf("-min-patch-coverage needs a -ref git range")
+	}
+
+	var results []model.GateResult
+	if cfg.MinCoverage != nil {
+		percent, minimum := data.Summary.Percent, *cfg.MinCoverage
+		result := model.GateResult{Name: "min-coverage", Passed: percent >= minimum}
+		if result.Passed {
+			result.Message = fmt.Sprintf("coverage %.1f%% meets the minimum of %.1f%%", percent, minimum)
+		} else {
+			result.Message = fmt.Sprintf("coverage %.1f%% is below the minimum of %.1f%%", percent, minimum)
+		}
+		results = append(results, result)
+	}
+	if cfg.MaxRegressions != nil {
+		regressions, maximum := data.DiffSummary.NewlyUncoveredLines, *cfg.MaxRegressions
+		result := model.GateResult{Name: "max-regressions", Passed: regressions <= maximum}
+		if result.Passed {
+			result.Message = fmt.Sprintf("%d lines lost coverage, at most %d allowed", regressions, maximum)
+		} else {
+			result.Message = fmt.Sprintf("%d lines lost coverage, more than the %d allowed", regressions, maximum)
+		}
+		results = append(results, result)
+	}
+	if cfg.MinDelta != nil {
+		delta, minimum := data.DiffSummary.DeltaPercent, *cfg.MinDelta
+		result := model.GateResult{Name: "min-delta", Passed: delta >= minimum}
+		if result.Passed {
+			result.Message = fmt.Sprintf("coverage changed by %+.1f%%, meets the minimum of %+.1f%%", delta, minimum)
+		} else {
+			result.Message = fmt.Sprintf("coverage changed by %+.1f%%, below the minimum of %+.1f%%", delta, minimum)
+		}
+		results = append(results, result)
+	}
+	if cfg.MinPatchCoverage != nil {
+		patch, minimum := data.Patch, *cfg.MinPatchCoverage
+		result := model.GateResult{Name: "min-patch-coverage", Passed: patch.TotalLines == 0 || patch.Percent >= minimum}
+		switch {
+		case patch.TotalLines == 0:
+			result.Message = "no changed lines with statements"
+		case result.Passed:
+			result.Message = fmt.Sprintf("patch coverage %.1f%% meets the minimum of %.1f%%", patch.Percent, minimum)
+		default:
+			result.Message = fmt.Sprintf("patch coverage %.1f%% is below the minimum of %.1f%%", patch.Percent, minimum)
+		}
+		results = append(results, result)
+	}
+	results = append(results, checkRules(data, cfg.Rules)...)
+	return results, nil
+}
+
+// Failed returns the results of the gates that did not pass.
+func Failed(results []model.GateResult) []model.GateResult {
+	var failed []model.GateResult
+	for _, r := range results {
+		if !r.Passed {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
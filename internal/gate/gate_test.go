@@ -0,0 +1,170 @@
+package gate
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"github.com/chmouel/go-better-html-coverage/internal/parser"
+)
+
+func TestCheck(t *testing.T) {
+	data := &model.CoverageData{
+		Summary:     model.Summary{Percent: 72.5},
+		IsDiffMode:  true,
+		DiffSummary: &model.DiffSummary{NewlyUncoveredLines: 4, DeltaPercent: -1.5},
+	}
+	minCoverage, lowCoverage := 80.0, 70.0
+	maxRegressions, manyRegressions := 3, 4
+	minDelta, lowDelta := 0.0, -2.0
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []string // name of each result, prefixed with ! when it failed
+		message string   // substring of the first message
+	}{
+		{
+			name: "no gates",
+		},
+		{
+			name:    "min coverage failed",
+			cfg:     Config{MinCoverage: &minCoverage},
+			want:    []string{"!min-coverage"},
+			message: "coverage 72.5% is below the minimum of 80.0%",
+		},
+		{
+			name:    "min coverage passed",
+			cfg:     Config{MinCoverage: &lowCoverage},
+			want:    []string{"min-coverage"},
+			message: "meets the minimum of 70.0%",
+		},
+		{
+			name:    "max regressions",
+			cfg:     Config{MaxRegressions: &maxRegressions},
+			want:    []string{"!max-regressions"},
+			message: "4 lines lost coverage, more than the 3 allowed",
+		},
+		{
+			name: "max regressions at the limit",
+			cfg:  Config{MaxRegressions: &manyRegressions},
+			want: []string{"max-regressions"},
+		},
+		{
+			name:    "min delta",
+			cfg:     Config{MinDelta: &minDelta},
+			want:    []string{"!min-delta"},
+			message: "coverage changed by -1.5%, below the minimum of +0.0%",
+		},
+		{
+			name: "all gates",
+			cfg:  Config{MinCoverage: &lowCoverage, MaxRegressions: &maxRegressions, MinDelta: &lowDelta},
+			want: []string{"min-coverage", "!max-regressions", "min-delta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Check(data, tt.cfg)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				name := r.Name
+				if !r.Passed {
+					name = "!" + name
+				}
+				got = append(got, name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if tt.message != "" && !strings.Contains(results[0].Message, tt.message) {
+				t.Errorf("expected message to contain %q, got %q", tt.message, results[0].Message)
+			}
+			if len(Failed(results)) != strings.Count(strings.Join(tt.want, ","), "!") {
+				t.Errorf("unexpected failed gates: %v", Failed(results))
+			}
+		})
+	}
+}
+
+func TestCheckDiffGatesWithoutBase(t *testing.T) {
+	maxRegressions := 0
+	data := &model.CoverageData{Summary: model.Summary{Percent: 50}}
+	_, err := Check(data, Config{MaxRegressions: &maxRegressions})
+	if err == nil || !strings.Contains(err.Error(), "-base") {
+		t.Errorf("expected an error about -base, got %v", err)
+	}
+}
+
+func TestCheckDiffGatesAfterFiltering(t *testing.T) {
+	base := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "kept.go", Lines: []string{"a", "b"}, Coverage: []int{2, 2}},
+			{Path: "regressed.go", Lines: []string{"a", "b"}, Coverage: []int{2, 2}},
+		},
+		Summary: model.Summary{Metric: model.MetricLines},
+	}
+	current := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "kept.go", Lines: []string{"a", "b"}, Coverage: []int{2, 2}},
+			{Path: "regressed.go", Lines: []string{"a", "b"}, Coverage: []int{1, 1}},
+		},
+		Summary: model.Summary{Metric: model.MetricLines},
+	}
+	maxRegressions, minDelta := 0, 0.0
+	cfg := Config{MaxRegressions: &maxRegressions, MinDelta: &minDelta}
+
+	diff := parser.ComputeDiff(base, current)
+	results, err := Check(diff, cfg)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(Failed(results)) != 2 {
+		t.Errorf("expected both gates to fail before filtering, got %+v", results)
+	}
+
+	filtered := parser.FilterByRegex(diff, []*regexp.Regexp{regexp.MustCompile(`^regressed\.go$`)})
+	results, err = Check(filtered, cfg)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if failed := Failed(results); len(results) != 2 || len(failed) != 0 {
+		t.Errorf("expected the gates to pass once the regressed file is excluded, got %+v", results)
+	}
+}
+
+func TestCheckPatchCoverage(t *testing.T) {
+	minimum := 80.0
+	cfg := Config{MinPatchCoverage: &minimum}
+
+	tests := []struct {
+		name    string
+		patch   *model.PatchSummary
+		passed  bool
+		message string
+	}{
+		{"below", &model.PatchSummary{TotalLines: 10, CoveredLines: 6, Percent: 60}, false, "patch coverage 60.0% is below the minimum of 80.0%"},
+		{"above", &model.PatchSummary{TotalLines: 10, CoveredLines: 9, Percent: 90}, true, "patch coverage 90.0% meets the minimum of 80.0%"},
+		{"no statements changed", &model.PatchSummary{}, true, "no changed lines with statements"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Check(&model.CoverageData{Patch: tt.patch}, cfg)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if len(results) != 1 || results[0].Name != "min-patch-coverage" ||
+				results[0].Passed != tt.passed || results[0].Message != tt.message {
+				t.Errorf("unexpected results %+v", results)
+			}
+		})
+	}
+
+	if _, err := Check(&model.CoverageData{}, cfg); err == nil || !strings.Contains(err.Error(), "-ref") {
+		t.Errorf("expected an error about -ref, got %v", err)
+	}
+}
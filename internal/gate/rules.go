@@ -0,0 +1,144 @@
+package gate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// Rule requires a minimum coverage for the directories matching a pattern.
+//
+// A pattern ending in "/..." matches a directory and everything below it, as
+// a single aggregate, like Go package patterns. Any other pattern is a glob
+// matched against each directory on its own, such as "cmd/*". Patterns are
+// relative to the source root, with an optional "./" prefix.
+type Rule struct {
+	Pattern string
+	Min     float64
+}
+
+// ReadRules reads rules from a thresholds file.
+func ReadRules(name string) ([]Rule, error) {
+	f, err := os.Open(name) //nolint:gosec // G304: name is from the -thresholds argument
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ParseRules(f)
+}
+
+// ParseRules parses rules, one "pattern minimum" pair per line. Empty lines
+// and lines starting with # are ignored.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a pattern and a minimum percentage", n)
+		}
+		pattern := strings.TrimSuffix(strings.TrimPrefix(fields[0], "./"), "/")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", n, fields[0], err)
+		}
+		minimum, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid minimum: %w", n, err)
+		}
+		if minimum < 0 || minimum > 100 {
+			return nil, fmt.Errorf("line %d: minimum must be between 0 and 100", n)
+		}
+		rules = append(rules, Rule{Pattern: pattern, Min: minimum})
+	}
+	return rules, scanner.Err()
+}
+
+// checkRules evaluates rules against the totals of the directories of the
+// files, with the coverage metric of the report.
+func checkRules(data *model.CoverageData, rules []Rule) []model.GateResult {
+	metric := data.Summary.Metric
+	if metric == "" {
+		metric = model.MetricLines
+	}
+
+	dirs := make(map[string]*model.Totals)
+	for _, f := range data.Files {
+		dir := path.Dir(f.Path)
+		if dirs[dir] == nil {
+			dirs[dir] = &model.Totals{}
+		}
+		dirs[dir].Add(f.Totals())
+	}
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []model.GateResult
+	for _, rule := range rules {
+		check := func(dir string, totals model.Totals) {
+			percent := totals.Percent(metric)
+			result := model.GateResult{Name: "thresholds", Path: dir, Passed: percent >= rule.Min}
+			if result.Passed {
+				result.Message = fmt.Sprintf("%s: %s %.1f%% meets the minimum of %.1f%%", rule.Pattern, dir, percent, rule.Min)
+			} else {
+				result.Message = fmt.Sprintf("%s: %s %.1f%% is below the minimum of %.1f%%", rule.Pattern, dir, percent, rule.Min)
+			}
+			results = append(results, result)
+		}
+
+		matched := false
+		if base, ok := recursiveBase(rule.Pattern); ok {
+			var totals model.Totals
+			for _, name := range names {
+				if base == "." || name == base || strings.HasPrefix(name, base+"/") {
+					totals.Add(*dirs[name])
+					matched = true
+				}
+			}
+			if matched {
+				check(base, totals)
+			}
+		} else {
+			for _, name := range names {
+				if ok, _ := path.Match(rule.Pattern, name); ok {
+					check(name, *dirs[name])
+					matched = true
+				}
+			}
+		}
+		// A rule matching nothing is likely a typo, it fails rather than
+		// passing silently, unless -ref left only the changed files
+		if !matched {
+			result := model.GateResult{Name: "thresholds", Passed: data.Patch != nil}
+			if result.Passed {
+				result.Message = fmt.Sprintf("%s: matches no changed files", rule.Pattern)
+			} else {
+				result.Message = fmt.Sprintf("%s: matches no directory with files, check the pattern", rule.Pattern)
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// recursiveBase returns the directory of a pattern ending in "...".
+func recursiveBase(pattern string) (string, bool) {
+	if pattern == "..." {
+		return ".", true
+	}
+	base, ok := strings.CutSuffix(pattern, "/...")
+	return base, ok
+}
@@ -0,0 +1,144 @@
+package gate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Rule
+		wantErr string
+	}{
+		{
+			name:  "rules",
+			input: "# api must stay well tested\n./internal/api/... 85\n\ncmd/*\t30%\n",
+			want:  []Rule{{Pattern: "internal/api/...", Min: 85}, {Pattern: "cmd/*", Min: 30}},
+		},
+		{
+			name:    "missing minimum",
+			input:   "internal/api\n",
+			wantErr: "line 1: expected a pattern and a minimum percentage",
+		},
+		{
+			name:    "invalid minimum",
+			input:   "# comment\ninternal/api high\n",
+			wantErr: "line 2: invalid minimum",
+		},
+		{
+			name:    "out of range",
+			input:   "internal/api 120\n",
+			wantErr: "between 0 and 100",
+		},
+		{
+			name:    "invalid pattern",
+			input:   "internal/[api 50\n",
+			wantErr: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRules failed: %v", err)
+			}
+			if len(rules) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, rules)
+			}
+			for i := range tt.want {
+				if rules[i] != tt.want[i] {
+					t.Errorf("rule %d: expected %v, got %v", i, tt.want[i], rules[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds")
+	if err := os.WriteFile(path, []byte("cmd/... 30\n"), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to write thresholds: %v", err)
+	}
+	rules, err := ReadRules(path)
+	if err != nil || len(rules) != 1 || rules[0].Pattern != "cmd/..." {
+		t.Errorf("unexpected rules %v, error %v", rules, err)
+	}
+	if _, err := ReadRules(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	file := func(path string, total, covered int) model.FileData {
+		coverage := make([]int, total)
+		for i := range coverage {
+			coverage[i] = 1
+			if i < covered {
+				coverage[i] = 2
+			}
+		}
+		return model.FileData{Path: path, Coverage: coverage}
+	}
+	data := &model.CoverageData{
+		Summary: model.Summary{Metric: model.MetricLines},
+		Files: []model.FileData{
+			file("cmd/app/main.go", 10, 2),
+			file("cmd/tool/main.go", 10, 5),
+			file("internal/api/api.go", 10, 9),
+			file("internal/api/v2/api.go", 10, 7),
+			file("main.go", 10, 10),
+		},
+	}
+
+	tests := []struct {
+		rule Rule
+		want []string // path of each result, prefixed with ! when it failed
+	}{
+		{Rule{Pattern: "internal/api/...", Min: 80}, []string{"internal/api"}},
+		{Rule{Pattern: "internal/api/...", Min: 85}, []string{"!internal/api"}},
+		{Rule{Pattern: "internal/api", Min: 85}, []string{"internal/api"}},
+		{Rule{Pattern: "cmd/*", Min: 30}, []string{"!cmd/app", "cmd/tool"}},
+		{Rule{Pattern: "...", Min: 66}, []string{"."}},
+		{Rule{Pattern: "pkg/...", Min: 50}, []string{"!"}},
+		{Rule{Pattern: "internal/apii/...", Min: 0}, []string{"!"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule.Pattern, func(t *testing.T) {
+			var got []string
+			for _, r := range checkRules(data, []Rule{tt.rule}) {
+				if r.Name != "thresholds" {
+					t.Errorf("unexpected gate name %q", r.Name)
+				}
+				p := r.Path
+				if !r.Passed {
+					p = "!" + p
+				}
+				got = append(got, p)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// With -ref, the rules of the directories left unchanged match nothing
+	data.Patch = &model.PatchSummary{}
+	results := checkRules(data, []Rule{{Pattern: "pkg/...", Min: 50}})
+	if len(results) != 1 || !results[0].Passed || results[0].Message != "pkg/...: matches no changed files" {
+		t.Errorf("expected an unmatched rule to pass with -ref, got %+v", results)
+	}
+}
@@ -12,9 +12,12 @@
   let currentMatchIndex = -1;
   let expandedDirs = new Set();
   let syntaxHighlightEnabled = config.syntaxEnabled;
-  let sortMode = 'name'; // 'name' or 'coverage'
+  let sortMode = 'name'; // 'name', 'coverage' or 'delta' (diff mode)
+  let treeFilter = 'all'; // diff mode: 'all', 'regressions', 'new-uncovered', 'dropped' or 'changed'
   let anchorLine = null;        // First line clicked (anchor for shift-select)
   let selectedRange = null;     // { start: N, end: M } or null
+  let outlineVisible = true;
+  let outlineSort = 'line'; // 'line' or 'coverage'
 
   // DOM elements
   const fileTree = document.getElementById('file-tree');
@@ -31,9 +34,18 @@
   const helpModal = document.getElementById('help-modal');
   const closeHelp = document.getElementById('close-help');
   const helpToggle = document.getElementById('help-toggle');
+  const gateBanner = document.getElementById('gate-banner');
+  const missingSources = document.getElementById('missing-sources');
+  const deletedFiles = document.getElementById('deleted-files');
+  const treeFilterSelect = document.getElementById('tree-filter');
+  const outline = document.getElementById('outline');
+  const outlineList = document.getElementById('outline-list');
+  const outlineToggle = document.getElementById('outline-toggle');
 
   // Coverage cache: fileId -> percentage
   let coverageCache = new Map();
+  let totalsCache = new Map();
+  let diffStatsCache = new Map();
 
   function initCoverageCache() {
     data.files.forEach((file, idx) => {
@@ -41,34 +53,190 @@
     });
   }
 
+  // Percentage of the metric selected for the report: covered lines, or
+  // covered statements as reported by go test -cover
   function calculateFileCoverage(fileId) {
-    const file = data.files[fileId];
-    let totalStatements = 0;
-    let coveredStatements = 0;
+    return percent(fileTotals(data.files[fileId]));
+  }
+
+  function fileTotals(file) {
+    let total = 0;
+    let covered = 0;
 
-    file.coverage.forEach(cov => {
-      if (cov > 0) totalStatements++;
-      if (cov === 2) coveredStatements++;
+    if (data.summary.metric === 'statements') {
+      (file.blocks || []).forEach(block => {
+        total += block.numStmt;
+        if (block.count > 0) covered += block.numStmt;
+      });
+    } else {
+      file.coverage.forEach(cov => {
+        if (cov > 0) total++;
+        if (cov === 2) covered++;
+      });
+    }
+
+    return { covered, total };
+  }
+
+  function percent(totals) {
+    return totals.total === 0 ? 0 : (totals.covered / totals.total) * 100;
+  }
+
+  // Diff mode: base and current totals of the selected metric, and the line
+  // changes of a file or of all the files below a directory. base is null
+  // when no file of the node is in the base profile.
+  function calculateDiffStats(node) {
+    if (node.type === 'file') {
+      if (diffStatsCache.has(node.fileId)) return diffStatsCache.get(node.fileId);
+      const file = data.files[node.fileId];
+      const diff = file.diff || {};
+      let base = null;
+      if (file.base) {
+        base = data.summary.metric === 'statements'
+          ? { covered: file.base.coveredStatements, total: file.base.totalStatements }
+          : { covered: file.base.coveredLines, total: file.base.totalLines };
+      }
+      const stats = {
+        base,
+        current: fileTotals(file),
+        newlyCovered: diff.newlyCoveredLines || 0,
+        regressions: diff.newlyUncoveredLines || 0,
+        newlyPartial: diff.newlyPartialLines || 0,
+        newCodeCovered: diff.newCodeCoveredLines || 0,
+        newCodeUncovered: diff.newCodeUncoveredLines || 0
+      };
+      diffStatsCache.set(node.fileId, stats);
+      return stats;
+    }
+
+    const stats = {
+      base: null,
+      current: { covered: 0, total: 0 },
+      newlyCovered: 0,
+      regressions: 0,
+      newlyPartial: 0,
+      newCodeCovered: 0,
+      newCodeUncovered: 0
+    };
+    node.children?.forEach(child => {
+      const c = calculateDiffStats(child);
+      if (c.base) {
+        stats.base = stats.base || { covered: 0, total: 0 };
+        stats.base.covered += c.base.covered;
+        stats.base.total += c.base.total;
+      }
+      stats.current.covered += c.current.covered;
+      stats.current.total += c.current.total;
+      stats.newlyCovered += c.newlyCovered;
+      stats.regressions += c.regressions;
+      stats.newlyPartial += c.newlyPartial;
+      stats.newCodeCovered += c.newCodeCovered;
+      stats.newCodeUncovered += c.newCodeUncovered;
     });
+    return stats;
+  }
 
-    return totalStatements === 0 ? 0 : (coveredStatements / totalStatements) * 100;
+  // Coverage change of a node in percentage points, null for new code
+  function calculateDelta(stats) {
+    return stats.base ? percent(stats.current) - percent(stats.base) : null;
   }
 
+  // Whether a file passes the tree filter of diff mode
+  function matchesTreeFilter(fileId) {
+    if (!data.isDiffMode || treeFilter === 'all') return true;
+
+    const stats = calculateDiffStats({ type: 'file', fileId });
+    const delta = calculateDelta(stats);
+    switch (treeFilter) {
+      case 'regressions':
+        return stats.regressions > 0;
+      case 'new-uncovered':
+        return stats.newCodeUncovered > 0;
+      case 'dropped':
+        return delta !== null && delta < 0;
+      case 'changed':
+        return stats.base === null || stats.newlyCovered + stats.regressions + stats.newlyPartial +
+          stats.newCodeCovered + stats.newCodeUncovered > 0 || Math.abs(delta) >= 0.05;
+      default:
+        return true;
+    }
+  }
+
+  function formatDelta(delta) {
+    if (Math.abs(delta) < 0.05) return '\u00B10.0';
+    return (delta > 0 ? '+' : '') + delta.toFixed(1);
+  }
+
+  // Badge with the coverage of a node, and in diff mode its change since the
+  // base
+  function createCoverageBadge(node) {
+    const badge = document.createElement('span');
+    badge.className = 'coverage-badge';
+    badge.textContent = calculateDirectoryCoverage(node).toFixed(1) + '%';
+    if (!data.isDiffMode) return badge;
+
+    const stats = calculateDiffStats(node);
+    const delta = calculateDelta(stats);
+    const deltaEl = document.createElement('span');
+    deltaEl.className = 'delta-badge';
+    const lines = [];
+    if (delta === null) {
+      deltaEl.textContent = 'new';
+      deltaEl.classList.add('delta-new');
+      lines.push('New code: ' + percent(stats.current).toFixed(1) + '%');
+    } else {
+      deltaEl.textContent = formatDelta(delta);
+      if (Math.abs(delta) >= 0.05) {
+        deltaEl.classList.add(delta > 0 ? 'delta-up' : 'delta-down');
+      }
+      lines.push('Base ' + percent(stats.base).toFixed(1) + '% \u2192 ' +
+        percent(stats.current).toFixed(1) + '% (' + formatDelta(delta) + ')');
+    }
+    lines.push('+' + stats.newlyCovered + ' newly covered, -' + stats.regressions + ' regressions');
+    if (stats.newlyPartial > 0) {
+      lines.push(stats.newlyPartial + ' lines now partially covered');
+    }
+    if (stats.newCodeCovered + stats.newCodeUncovered > 0) {
+      lines.push('New code: ' + stats.newCodeCovered + ' covered, ' +
+        stats.newCodeUncovered + ' uncovered lines');
+    }
+    badge.title = lines.join('\n');
+    badge.appendChild(deltaEl);
+
+    if (stats.regressions > 0) {
+      const reg = document.createElement('span');
+      reg.className = 'regression-count';
+      reg.textContent = '-' + stats.regressions;
+      reg.title = stats.regressions + ' lines lost coverage';
+      badge.appendChild(reg);
+    }
+    return badge;
+  }
+
+  // Coverage of a file, or of the files below a directory from their summed
+  // covered and total counts, as in calculateDiffStats
   function calculateDirectoryCoverage(node) {
     if (node.type === 'file') {
       return coverageCache.get(node.fileId) || 0;
     }
+    return percent(directoryTotals(node));
+  }
 
-    let totalCoverage = 0;
-    let fileCount = 0;
+  function directoryTotals(node) {
+    if (node.type === 'file') {
+      if (!totalsCache.has(node.fileId)) {
+        totalsCache.set(node.fileId, fileTotals(data.files[node.fileId]));
+      }
+      return totalsCache.get(node.fileId);
+    }
 
+    const totals = { covered: 0, total: 0 };
     node.children?.forEach(child => {
-      const childCov = calculateDirectoryCoverage(child);
-      totalCoverage += childCov;
-      fileCount++;
+      const c = directoryTotals(child);
+      totals.covered += c.covered;
+      totals.total += c.total;
     });
-
-    return fileCount === 0 ? 0 : totalCoverage / fileCount;
+    return totals;
   }
 
   function sortTreeNodes(node, mode) {
@@ -91,6 +259,17 @@
         return aCov !== bCov ? bCov - aCov : a.name.localeCompare(b.name);
       }
 
+      if (mode === 'delta' && data.isDiffMode) {
+        // Ascending: biggest drop first, new code last
+        const aDelta = calculateDelta(calculateDiffStats(a));
+        const bDelta = calculateDelta(calculateDiffStats(b));
+        if (aDelta !== bDelta) {
+          if (aDelta === null) return 1;
+          if (bDelta === null) return -1;
+          return aDelta - bDelta;
+        }
+      }
+
       return a.name.localeCompare(b.name);
     });
 
@@ -102,10 +281,14 @@
     initCoverageCache();
     loadSortPreference();
     renderSummary();
+    renderGates();
+    renderMissingSources();
+    renderDeletedFiles();
     renderTree();
     setupEventListeners();
     loadTheme();
     loadSyntaxPreference();
+    loadOutlinePreference();
 
     // Check for deep link hash first, otherwise select first file
     if (!navigateToHash() && data.files.length > 0) {
@@ -220,12 +403,148 @@
       changesEl.style.marginTop = '4px';
       changesEl.textContent = '+' + data.diffSummary.newlyCoveredLines + ' covered, -' +
         data.diffSummary.newlyUncoveredLines + ' regressions';
+      if (data.diffSummary.newlyPartialLines > 0) {
+        changesEl.textContent += ', ' + data.diffSummary.newlyPartialLines + ' now partial';
+      }
       summary.appendChild(changesEl);
+
+      const newCodeEl = document.createElement('div');
+      newCodeEl.style.fontSize = '11px';
+      newCodeEl.style.marginTop = '4px';
+      newCodeEl.textContent = 'new code: ' + (data.diffSummary.newCodeCoveredLines || 0) + ' covered, ' +
+        (data.diffSummary.newCodeUncoveredLines || 0) + ' uncovered';
+      summary.appendChild(newCodeEl);
+
+      if (data.diffSummary.deletedFiles > 0 || data.diffSummary.renamedFiles > 0) {
+        const filesEl = document.createElement('div');
+        filesEl.style.fontSize = '11px';
+        filesEl.style.marginTop = '4px';
+        filesEl.textContent = data.diffSummary.deletedFiles + ' deleted, ' +
+          data.diffSummary.renamedFiles + ' renamed files';
+        summary.appendChild(filesEl);
+      }
     } else {
-      summary.appendChild(document.createTextNode(
-        ' coverage (' + data.summary.coveredLines + '/' + data.summary.totalLines + ' lines)'
-      ));
+      summary.appendChild(document.createTextNode(' ' + data.summary.metric + ' coverage'));
+      if (data.summary.partialLines > 0) {
+        const partialEl = document.createElement('div');
+        partialEl.style.fontSize = '11px';
+        partialEl.style.marginTop = '4px';
+        partialEl.textContent = data.summary.partialLines + ' partially covered lines';
+        summary.appendChild(partialEl);
+      }
+    }
+
+    // Coverage of the lines changed by the -ref git range
+    if (data.patch) {
+      const patchEl = document.createElement('div');
+      patchEl.style.fontSize = '11px';
+      patchEl.style.marginTop = '4px';
+      patchEl.textContent = data.patch.totalLines > 0
+        ? 'patch ' + data.patch.percent.toFixed(1) + '% (' + data.patch.coveredLines + '/' +
+          data.patch.totalLines + ' changed lines)'
+        : 'patch: no changed lines with statements';
+      summary.appendChild(patchEl);
     }
+
+    // Both metrics, the selected one first
+    const metricsEl = document.createElement('div');
+    metricsEl.style.fontSize = '11px';
+    metricsEl.style.marginTop = '4px';
+    const lines = 'lines ' + data.summary.linePercent.toFixed(1) + '% (' +
+      data.summary.coveredLines + '/' + data.summary.totalLines + ')';
+    const statements = 'statements ' + data.summary.statementPercent.toFixed(1) + '% (' +
+      data.summary.coveredStatements + '/' + data.summary.totalStatements + ')';
+    metricsEl.textContent = data.summary.metric === 'statements'
+      ? statements + ' \u00B7 ' + lines
+      : lines + ' \u00B7 ' + statements;
+    summary.appendChild(metricsEl);
+  }
+
+  // Show whether the coverage gates of the build passed, and why not
+  function renderGates() {
+    const gates = data.gates || [];
+    if (gates.length === 0) return;
+
+    const failed = gates.filter(g => !g.passed);
+    gateBanner.classList.remove('hidden');
+    gateBanner.classList.toggle('failed', failed.length > 0);
+    document.getElementById('gate-banner-title').textContent = failed.length > 0
+      ? '\u2717 ' + failed.length + ' coverage gate' + (failed.length === 1 ? '' : 's') + ' failed'
+      : '\u2713 Coverage gates passed';
+
+    const list = document.getElementById('gate-banner-list');
+    gates.forEach(g => {
+      const item = document.createElement('li');
+      item.className = g.passed ? 'gate-passed' : 'gate-failed';
+      item.textContent = (g.passed ? '\u2713 ' : '\u2717 ') + '-' + g.name + ': ' + g.message;
+      list.appendChild(item);
+    });
+  }
+
+  // List the profile entries whose source file could not be found
+  function renderMissingSources() {
+    const skipped = (data.diagnostics || []).filter(d => d.skipped);
+    if (skipped.length === 0) return;
+
+    missingSources.classList.remove('hidden');
+    document.getElementById('missing-sources-title').textContent =
+      '\u26A0 ' + skipped.length + ' missing source' + (skipped.length === 1 ? '' : 's');
+
+    const list = document.getElementById('missing-sources-list');
+    skipped.forEach(d => {
+      const item = document.createElement('li');
+
+      const name = document.createElement('div');
+      name.className = 'missing-file';
+      name.textContent = d.fileName;
+      item.appendChild(name);
+
+      const reason = document.createElement('div');
+      reason.className = 'missing-reason';
+      reason.textContent = d.reason;
+      item.appendChild(reason);
+
+      const attempts = document.createElement('ul');
+      attempts.className = 'missing-attempts';
+      (d.attempts || []).forEach(a => {
+        const attempt = document.createElement('li');
+        attempt.textContent = a.method + ': ' + a.path;
+        if (a.error) attempt.title = a.error;
+        attempts.appendChild(attempt);
+      });
+      item.appendChild(attempts);
+
+      list.appendChild(item);
+    });
+  }
+
+  // List the base files missing from the current profile in diff mode, with
+  // the coverage they took away
+  function renderDeletedFiles() {
+    const deleted = data.deletedFiles || [];
+    if (deleted.length === 0) return;
+
+    deletedFiles.classList.remove('hidden');
+    document.getElementById('deleted-files-title').textContent =
+      deleted.length + ' deleted file' + (deleted.length === 1 ? '' : 's') + ', -' +
+      data.diffSummary.deletedCoveredLines + ' covered lines';
+
+    const list = document.getElementById('deleted-files-list');
+    deleted.forEach(d => {
+      const item = document.createElement('li');
+
+      const name = document.createElement('div');
+      name.className = 'missing-file';
+      name.textContent = d.path;
+      item.appendChild(name);
+
+      const totals = document.createElement('div');
+      totals.className = 'missing-reason';
+      totals.textContent = d.totals.coveredLines + '/' + d.totals.totalLines + ' lines covered in the base';
+      item.appendChild(totals);
+
+      list.appendChild(item);
+    });
   }
 
   function renderTree() {
@@ -240,14 +559,18 @@
     }
     const sortedTree = sortTreeNodes(data.tree, sortMode);
     renderNode(sortedTree, fileTree, 0);
+    if (searchQuery || treeFilter !== 'all') {
+      filterTree();
+    }
   }
 
-  function renderNode(node, container, depth) {
+  function renderNode(node, container, depth, parentPath = '') {
     if (node.name === '.' && node.type === 'dir') {
       // Root node, render children directly
       node.children.forEach(child => renderNode(child, container, depth));
       return;
     }
+    const fullPath = parentPath ? parentPath + '/' + node.name : node.name;
 
     const nodeEl = document.createElement('div');
     nodeEl.className = 'tree-node';
@@ -280,17 +603,23 @@
       item.appendChild(name);
 
       // Add coverage badge to all directories
-      const badge = document.createElement('span');
-      badge.className = 'coverage-badge';
-      badge.textContent = calculateDirectoryCoverage(node).toFixed(1) + '%';
+      const badge = createCoverageBadge(node);
       item.appendChild(badge);
 
+      // Highlight the directories checked by -thresholds rules
+      const rules = (data.gates || []).filter(g => g.name === 'thresholds' && g.path === fullPath);
+      if (rules.length > 0) {
+        item.classList.add(rules.some(g => !g.passed) ? 'threshold-failed' : 'threshold-passed');
+        const ruleLines = rules.map(g => (g.passed ? '\u2713 ' : '\u2717 ') + g.message);
+        badge.title = badge.title ? badge.title + '\n' + ruleLines.join('\n') : ruleLines.join('\n');
+      }
+
       nodeEl.appendChild(item);
 
       if (node.children && node.children.length > 0) {
         const children = document.createElement('div');
         children.className = 'tree-children';
-        node.children.forEach(child => renderNode(child, children, depth + 1));
+        node.children.forEach(child => renderNode(child, children, depth + 1, fullPath));
         nodeEl.appendChild(children);
       }
     } else {
@@ -305,11 +634,27 @@
       item.appendChild(icon);
       item.appendChild(name);
 
+      // Flag files that no test binary compiled
+      if (data.files[node.fileId].notInProfile) {
+        item.classList.add('not-in-profile');
+        const tag = document.createElement('span');
+        tag.className = 'untested-tag';
+        tag.textContent = 'not in profile';
+        tag.title = 'This file is not in the coverage profile: no test covers its package';
+        item.appendChild(tag);
+      }
+
+      // Flag files compared with a base file of another path
+      if (data.files[node.fileId].basePath) {
+        const tag = document.createElement('span');
+        tag.className = 'renamed-tag';
+        tag.textContent = 'renamed';
+        tag.title = 'Renamed from ' + data.files[node.fileId].basePath;
+        item.appendChild(tag);
+      }
+
       // Add coverage badge to files
-      const badge = document.createElement('span');
-      badge.className = 'coverage-badge';
-      badge.textContent = calculateDirectoryCoverage(node).toFixed(1) + '%';
-      item.appendChild(badge);
+      item.appendChild(createCoverageBadge(node));
 
       nodeEl.appendChild(item);
     }
@@ -357,6 +702,7 @@
 
     filePath.textContent = file.path;
     renderCode(file);
+    renderOutline(file);
 
     // Update URL hash for deep linking
     updateHash(fileId, null);
@@ -382,6 +728,21 @@
     const container = document.createElement('div');
     container.className = 'code-container';
 
+    // Heatmap gutter for count/atomic profiles, scaled to the file's hottest line
+    const heatmap = isHeatmapMode() && !data.isDiffMode && file.counts;
+    const maxCount = heatmap ? file.counts.reduce((max, count) => Math.max(max, count), 0) : 0;
+
+    // Lines changed by the -ref git range, outlined hunk by hunk
+    const hunkClasses = new Map();
+    (file.hunks || []).forEach(h => {
+      for (let n = h.start; n <= h.end; n++) {
+        const classes = ['changed'];
+        if (n === h.start) classes.push('hunk-start');
+        if (n === h.end) classes.push('hunk-end');
+        hunkClasses.set(n, classes);
+      }
+    });
+
     file.lines.forEach((line, idx) => {
       const cov = file.coverage[idx];
       const diff = file.diffState ? file.diffState[idx] : null;
@@ -399,6 +760,15 @@
           case 2: // newly uncovered (regression)
             lineEl.classList.add('newly-uncovered');
             break;
+          case 5: // new code covered
+            lineEl.classList.add('new-code-covered');
+            break;
+          case 6: // new code uncovered
+            lineEl.classList.add('new-code-uncovered');
+            break;
+          case 7: // newly partial
+            lineEl.classList.add('newly-partial');
+            break;
           case 3: // unchanged covered
           case 4: // unchanged uncovered
           case 0: // no change
@@ -411,9 +781,13 @@
           lineEl.classList.add('covered');
         } else if (cov === 1) {
           lineEl.classList.add('uncovered');
+        } else if (cov === 3) {
+          lineEl.classList.add('partial');
         }
       }
 
+      (hunkClasses.get(idx + 1) || []).forEach(c => lineEl.classList.add(c));
+
       const gutter = document.createElement('div');
       gutter.className = 'gutter';
 
@@ -422,6 +796,13 @@
       lineNum.textContent = idx + 1;
       lineNum.title = 'Click to select line, Shift+Click for range';
 
+      if (heatmap && cov > 0) {
+        const count = file.counts[idx] || 0;
+        gutter.classList.add('heat-' + heatLevel(count, maxCount));
+        gutter.title = formatHits(count);
+        lineNum.title = formatHits(count) + '\n' + lineNum.title;
+      }
+
       // Add click handler for line number deep linking
       const lineNumber = idx + 1;
       lineNum.addEventListener('click', (e) => {
@@ -445,7 +826,11 @@
 
       const content = document.createElement('div');
       content.className = 'line-content';
-      content.textContent = line || ' ';
+      if (cov === 3 && !data.isDiffMode && file.blocks) {
+        renderPartialLine(content, line, uncoveredRanges(file, idx + 1, line));
+      } else {
+        content.textContent = line || ' ';
+      }
 
       lineEl.appendChild(gutter);
       lineEl.appendChild(lineNum);
@@ -461,6 +846,196 @@
     }
   }
 
+  // Character ranges of a line covered by blocks that never ran. Block
+  // columns are 1-based UTF-8 byte offsets, converted here to string indexes.
+  function uncoveredRanges(file, lineNumber, text) {
+    const offsets = byteOffsets(text);
+    const ranges = [];
+
+    file.blocks.forEach(b => {
+      if (b.numStmt === 0 || b.count > 0) return;
+      if (lineNumber < b.startLine || lineNumber > b.endLine) return;
+
+      let start = lineNumber === b.startLine ? offsets.indexOf(b.startCol - 1) : 0;
+      let end = lineNumber === b.endLine ? offsets.indexOf(b.endCol - 1) : text.length;
+      if (start === -1) start = 0;
+      if (end === -1) end = text.length;
+
+      // Don't paint leading/trailing whitespace
+      while (start < end && /\s/.test(text[start])) start++;
+      while (end > start && /\s/.test(text[end - 1])) end--;
+      if (start < end) ranges.push({ start: start, end: end });
+    });
+
+    return ranges.sort((a, b) => a.start - b.start);
+  }
+
+  // Map each string index (plus the end of the string) to its UTF-8 byte offset
+  function byteOffsets(text) {
+    const offsets = [];
+    let bytes = 0;
+    for (let i = 0; i < text.length; i++) {
+      offsets.push(bytes);
+      const code = text.charCodeAt(i);
+      if (code < 0x80) {
+        bytes += 1;
+      } else if (code < 0x800) {
+        bytes += 2;
+      } else if (code >= 0xD800 && code <= 0xDBFF) {
+        // Surrogate pair: 4 bytes for both halves
+        offsets.push(bytes);
+        bytes += 4;
+        i++;
+      } else {
+        bytes += 3;
+      }
+    }
+    offsets.push(bytes);
+    return offsets;
+  }
+
+  function renderPartialLine(content, text, ranges) {
+    let lastEnd = 0;
+    ranges.forEach(r => {
+      if (r.start < lastEnd) return;
+      if (r.start > lastEnd) {
+        content.appendChild(document.createTextNode(text.substring(lastEnd, r.start)));
+      }
+      const span = document.createElement('span');
+      span.className = 'uncovered-range';
+      span.title = 'Not executed';
+      span.textContent = text.substring(r.start, r.end);
+      content.appendChild(span);
+      lastEnd = r.end;
+    });
+    if (lastEnd < text.length) {
+      content.appendChild(document.createTextNode(text.substring(lastEnd)));
+    }
+    if (content.childNodes.length === 0) {
+      content.textContent = ' ';
+    }
+  }
+
+  function isHeatmapMode() {
+    return data.mode === 'count' || data.mode === 'atomic';
+  }
+
+  // Map an execution count to a heat level from 0 (never run) to 5 (hottest),
+  // on a log scale so a handful of hot loops don't flatten everything else.
+  function heatLevel(count, maxCount) {
+    if (count <= 0) return 0;
+    if (maxCount <= 1) return 5;
+    const ratio = Math.log(count + 1) / Math.log(maxCount + 1);
+    return Math.max(1, Math.ceil(ratio * 5));
+  }
+
+  function formatHits(count) {
+    if (count <= 0) return 'Not executed';
+    return 'Executed ' + count.toLocaleString('en-US') + (count === 1 ? ' time' : ' times');
+  }
+
+  function functionDisplayName(fn) {
+    if (!fn.receiver) return fn.name;
+    if (fn.receiver.startsWith('*')) return '(' + fn.receiver + ').' + fn.name;
+    return fn.receiver + '.' + fn.name;
+  }
+
+  function renderOutline(file) {
+    outlineList.textContent = '';
+
+    const functions = [...(file.functions || [])];
+    if (functions.length === 0) {
+      const empty = document.createElement('div');
+      empty.className = 'outline-empty';
+      empty.textContent = 'No functions';
+      outlineList.appendChild(empty);
+      return;
+    }
+
+    if (outlineSort === 'coverage') {
+      // Ascending: least covered first, functions without statements last
+      functions.sort((a, b) => {
+        if ((a.statements === 0) !== (b.statements === 0)) return a.statements === 0 ? 1 : -1;
+        return a.percent !== b.percent ? a.percent - b.percent : a.startLine - b.startLine;
+      });
+    }
+
+    functions.forEach(fn => {
+      const item = document.createElement('div');
+      item.className = 'outline-item';
+      item.title = functionDisplayName(fn) + ' (lines ' + fn.startLine + '-' + fn.endLine + ', ' +
+        fn.covered + '/' + fn.statements + ' statements)';
+
+      const name = document.createElement('span');
+      name.className = 'name';
+      name.textContent = functionDisplayName(fn);
+
+      const badge = document.createElement('span');
+      badge.className = 'coverage-badge';
+      if (fn.statements === 0) {
+        badge.textContent = '-';
+      } else {
+        badge.textContent = fn.percent.toFixed(1) + '%';
+        if (fn.covered === 0) {
+          item.classList.add('uncovered');
+        } else if (fn.covered < fn.statements) {
+          item.classList.add('partial');
+        } else {
+          item.classList.add('covered');
+        }
+      }
+
+      item.appendChild(name);
+      item.appendChild(badge);
+      item.addEventListener('click', () => {
+        anchorLine = fn.startLine;
+        selectedRange = { start: fn.startLine, end: fn.startLine };
+        selectLineRange(fn.startLine, fn.startLine);
+        scrollToLine(fn.startLine);
+        updateHash(currentFileId, fn.startLine, null);
+      });
+      outlineList.appendChild(item);
+    });
+  }
+
+  function toggleOutline() {
+    outlineVisible = !outlineVisible;
+    localStorage.setItem('coverage-outline', outlineVisible ? 'on' : 'off');
+    applyOutlineVisibility();
+  }
+
+  function applyOutlineVisibility() {
+    outline.classList.toggle('hidden', !outlineVisible);
+    outlineToggle.classList.toggle('active', outlineVisible);
+  }
+
+  function changeOutlineSort(mode) {
+    if (outlineSort === mode) return;
+    outlineSort = mode;
+    localStorage.setItem('coverage-outline-sort', mode);
+    document.querySelectorAll('.outline-sort-btn').forEach(btn => {
+      btn.classList.toggle('active', btn.dataset.sort === mode);
+    });
+    if (currentFileId !== null) {
+      renderOutline(data.files[currentFileId]);
+    }
+  }
+
+  function loadOutlinePreference() {
+    const saved = localStorage.getItem('coverage-outline');
+    if (saved !== null) {
+      outlineVisible = saved === 'on';
+    }
+    const savedSort = localStorage.getItem('coverage-outline-sort');
+    if (savedSort === 'line' || savedSort === 'coverage') {
+      outlineSort = savedSort;
+    }
+    document.querySelectorAll('.outline-sort-btn').forEach(btn => {
+      btn.classList.toggle('active', btn.dataset.sort === outlineSort);
+    });
+    applyOutlineVisibility();
+  }
+
   function setupEventListeners() {
     // File search
     let searchTimeout;
@@ -501,6 +1076,12 @@
     // Syntax toggle
     syntaxToggle.addEventListener('click', toggleSyntax);
 
+    // Function outline
+    outlineToggle.addEventListener('click', toggleOutline);
+    document.querySelectorAll('.outline-sort-btn').forEach(btn => {
+      btn.addEventListener('click', () => changeOutlineSort(btn.dataset.sort));
+    });
+
     // Sort controls
     const sortButtons = document.querySelectorAll('.sort-btn');
     console.log('Found', sortButtons.length, 'sort buttons');
@@ -512,6 +1093,12 @@
       });
     });
 
+    // Tree filter, diff mode only
+    treeFilterSelect.addEventListener('change', () => {
+      treeFilter = treeFilterSelect.value;
+      filterTree();
+    });
+
     // Keyboard shortcuts
     document.addEventListener('keydown', (e) => {
       if ((e.ctrlKey || e.metaKey) && e.key === 'f' && currentFileId !== null) {
@@ -563,28 +1150,28 @@
 
   function filterTree() {
     const nodes = document.querySelectorAll('.tree-node');
+    const filtering = data.isDiffMode && treeFilter !== 'all';
 
-    if (!searchQuery) {
+    if (!searchQuery && !filtering) {
       nodes.forEach(n => n.classList.remove('hidden'));
       return;
     }
 
+    const isVisible = (fid) => {
+      const file = data.files[fid];
+      return file && file.path.toLowerCase().includes(searchQuery) && matchesTreeFilter(fid);
+    };
+
     nodes.forEach(node => {
-      const name = node.dataset.name || '';
       const fileId = node.dataset.fileId;
 
       if (fileId !== undefined) {
-        const file = data.files[parseInt(fileId)];
-        const matchesQuery = file && file.path.toLowerCase().includes(searchQuery);
-        node.classList.toggle('hidden', !matchesQuery);
+        node.classList.toggle('hidden', !isVisible(parseInt(fileId)));
       } else {
-        const hasVisibleChild = Array.from(node.querySelectorAll('[data-file-id]')).some(f => {
-          const fid = parseInt(f.dataset.fileId);
-          const file = data.files[fid];
-          return file && file.path.toLowerCase().includes(searchQuery);
-        });
+        const hasVisibleChild = Array.from(node.querySelectorAll('[data-file-id]'))
+          .some(f => isVisible(parseInt(f.dataset.fileId)));
         node.classList.toggle('hidden', !hasVisibleChild);
-        if (hasVisibleChild && searchQuery) {
+        if (hasVisibleChild) {
           node.classList.add('expanded');
           const icon = node.querySelector('.icon');
           if (icon && icon.textContent === '\u25B6') {
@@ -818,10 +1405,14 @@
 
   function loadSortPreference() {
     const saved = localStorage.getItem('coverage-sort-mode');
-    if (saved && (saved === 'name' || saved === 'coverage')) {
+    if (saved && (saved === 'name' || saved === 'coverage' || (saved === 'delta' && data.isDiffMode))) {
       sortMode = saved;
     }
 
+    // Sorting by delta and filtering the tree need a base
+    document.getElementById('sort-delta').classList.toggle('hidden', !data.isDiffMode);
+    document.getElementById('tree-filter-box').classList.toggle('hidden', !data.isDiffMode);
+
     // Update button states
     document.querySelectorAll('.sort-btn').forEach(btn => {
       btn.classList.toggle('active', btn.dataset.sort === sortMode);
@@ -1,12 +1,132 @@
 package model
 
+// Block is a single coverage profile block, as recorded by the Go cover tool.
+// Columns are 1-based byte offsets, StartCol inclusive and EndCol exclusive.
+type Block struct {
+	StartLine int `json:"startLine"`
+	StartCol  int `json:"startCol"`
+	EndLine   int `json:"endLine"`
+	EndCol    int `json:"endCol"`
+	NumStmt   int `json:"numStmt"`
+	Count     int `json:"count"` // execution count (0/1 in set mode)
+}
+
+// Function is a function or method declaration with its statement coverage.
+type Function struct {
+	Name       string  `json:"name"`
+	Receiver   string  `json:"receiver,omitempty"` // receiver type for methods, e.g. "*Parser"
+	StartLine  int     `json:"startLine"`
+	EndLine    int     `json:"endLine"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"` // covered statements
+	Percent    float64 `json:"percent"`
+}
+
+// DisplayName returns the function name qualified by its receiver, in the
+// form used by Go tooling, e.g. "(*Parser).Parse".
+func (f Function) DisplayName() string {
+	switch {
+	case f.Receiver == "":
+		return f.Name
+	case f.Receiver[0] == '*':
+		return "(" + f.Receiver + ")." + f.Name
+	default:
+		return f.Receiver + "." + f.Name
+	}
+}
+
 // FileData represents a single source file with coverage information.
 type FileData struct {
-	ID        int      `json:"id"`
-	Path      string   `json:"path"`                // module-relative path
-	Lines     []string `json:"lines"`               // source lines
-	Coverage  []int    `json:"coverage"`            // 0=no stmt, 1=uncovered, 2=covered
-	DiffState []int    `json:"diffState,omitempty"` // diff mode only: 0=no change, 1=newly covered, 2=newly uncovered, 3=unchanged covered, 4=unchanged uncovered
+	ID        int        `json:"id"`
+	Path      string     `json:"path"`                // module-relative path
+	Lines     []string   `json:"lines"`               // source lines
+	Coverage  []int      `json:"coverage"`            // 0=no stmt, 1=uncovered, 2=covered, 3=partially covered
+	Counts    []int      `json:"counts,omitempty"`    // highest execution count of the blocks on each line
+	Blocks    []Block    `json:"blocks,omitempty"`    // profile blocks for this file
+	Functions []Function `json:"functions,omitempty"` // function and method declarations
+	DiffState []int      `json:"diffState,omitempty"` // diff mode only: 0=no change, 1=newly covered, 2=newly uncovered, 3=unchanged covered, 4=unchanged uncovered, 5=new code covered, 6=new code uncovered, 7=newly partial
+
+	NotInProfile bool `json:"notInProfile,omitempty"` // source file missing from the profile, all statements uncovered
+
+	Base *Totals `json:"base,omitempty"` // diff mode only: totals of the file in the base profile, nil for new files
+
+	Hunks []Hunk `json:"hunks,omitempty"` // lines added or modified by the -ref git range, or since the base in diff mode
+
+	BasePath string `json:"basePath,omitempty"` // diff mode only: path of the file in the base when it was renamed
+
+	Diff *FileDiff `json:"diff,omitempty"` // diff mode only: line changes since the base
+}
+
+// FileDiff counts the lines of a file whose coverage changed since the base,
+// in diff mode. Together with the base totals of the file, it gives the base
+// and current percentages of the file and of the directories holding it.
+type FileDiff struct {
+	NewlyCoveredLines     int `json:"newlyCoveredLines"`     // existing code, now covered or covered more
+	NewlyUncoveredLines   int `json:"newlyUncoveredLines"`   // existing code that lost coverage (regressions)
+	NewlyPartialLines     int `json:"newlyPartialLines"`     // existing code, was covered, now partially covered
+	NewCodeCoveredLines   int `json:"newCodeCoveredLines"`   // new code, covered
+	NewCodeUncoveredLines int `json:"newCodeUncoveredLines"` // new code, not covered
+}
+
+// Hunk is a range of lines added or modified by a change, 1-based and
+// inclusive.
+type Hunk struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Totals returns the line and statement counts of the file.
+func (f FileData) Totals() Totals {
+	var t Totals
+	for _, c := range f.Coverage {
+		if c > 0 {
+			t.TotalLines++
+		}
+		switch c {
+		case 2:
+			t.CoveredLines++
+		case 3:
+			t.PartialLines++
+		}
+	}
+	for _, b := range f.Blocks {
+		t.TotalStatements += b.NumStmt
+		if b.Count > 0 {
+			t.CoveredStatements += b.NumStmt
+		}
+	}
+	return t
+}
+
+// Totals holds the line and statement counts of one or more files.
+type Totals struct {
+	TotalLines        int `json:"totalLines"`
+	CoveredLines      int `json:"coveredLines"`
+	PartialLines      int `json:"partialLines"`
+	TotalStatements   int `json:"totalStatements"`
+	CoveredStatements int `json:"coveredStatements"`
+}
+
+// Add adds the counts of o to t.
+func (t *Totals) Add(o Totals) {
+	t.TotalLines += o.TotalLines
+	t.CoveredLines += o.CoveredLines
+	t.PartialLines += o.PartialLines
+	t.TotalStatements += o.TotalStatements
+	t.CoveredStatements += o.CoveredStatements
+}
+
+// Percent returns the coverage percentage for metric, MetricLines or
+// MetricStatements, 0 when there is nothing to cover.
+func (t Totals) Percent(metric string) float64 {
+	covered, total := t.CoveredLines, t.TotalLines
+	if metric == MetricStatements {
+		covered, total = t.CoveredStatements, t.TotalStatements
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total) * 100
 }
 
 // TreeNode represents a node in the file tree (directory or file).
@@ -17,26 +137,114 @@ type TreeNode struct {
 	Children []*TreeNode `json:"children,omitempty"`
 }
 
+// Coverage metrics a summary percentage can be based on.
+const (
+	MetricLines      = "lines"      // share of lines with statements that are fully covered
+	MetricStatements = "statements" // share of statements covered, as "go test -cover" reports
+)
+
 // Summary contains overall coverage statistics.
 type Summary struct {
+	TotalLines        int     `json:"totalLines"`
+	CoveredLines      int     `json:"coveredLines"`
+	PartialLines      int     `json:"partialLines"` // lines with both covered and uncovered code, not counted as covered
+	TotalStatements   int     `json:"totalStatements"`
+	CoveredStatements int     `json:"coveredStatements"`
+	LinePercent       float64 `json:"linePercent"`
+	StatementPercent  float64 `json:"statementPercent"`
+	Metric            string  `json:"metric"`  // metric Percent is based on, MetricLines or MetricStatements
+	Percent           float64 `json:"percent"` // percentage of the selected metric
+}
+
+// DiffSummary contains statistics about coverage changes between base and
+// current. New code is the lines that had no statement in the base, existing
+// code the others.
+type DiffSummary struct {
+	NewlyCoveredLines     int     `json:"newlyCoveredLines"`     // existing code, now covered or covered more
+	NewlyUncoveredLines   int     `json:"newlyUncoveredLines"`   // existing code that lost coverage (regressions)
+	NewlyPartialLines     int     `json:"newlyPartialLines"`     // existing code, was covered, now partially covered
+	NewCodeCoveredLines   int     `json:"newCodeCoveredLines"`   // new code, covered
+	NewCodeUncoveredLines int     `json:"newCodeUncoveredLines"` // new code, not covered
+	DeletedFiles          int     `json:"deletedFiles"`          // base files missing from current
+	DeletedCoveredLines   int     `json:"deletedCoveredLines"`   // covered lines of the deleted files
+	RenamedFiles          int     `json:"renamedFiles"`          // files compared with a base file of another path
+	DeltaPercent          float64 `json:"deltaPercent"`
+	BasePercent           float64 `json:"basePercent"`
+}
+
+// Add adds the line changes of a file to s.
+func (s *DiffSummary) Add(d FileDiff) {
+	s.NewlyCoveredLines += d.NewlyCoveredLines
+	s.NewlyUncoveredLines += d.NewlyUncoveredLines
+	s.NewlyPartialLines += d.NewlyPartialLines
+	s.NewCodeCoveredLines += d.NewCodeCoveredLines
+	s.NewCodeUncoveredLines += d.NewCodeUncoveredLines
+}
+
+// DeletedFile is a file of the base profile missing from the current one, in
+// diff mode.
+type DeletedFile struct {
+	Path   string `json:"path"`
+	Totals Totals `json:"totals"` // counts of the file in the base
+}
+
+// PatchSummary contains coverage statistics over the lines changed by the
+// git range of -ref, counting only the lines with statements.
+type PatchSummary struct {
 	TotalLines   int     `json:"totalLines"`
 	CoveredLines int     `json:"coveredLines"`
+	PartialLines int     `json:"partialLines"`
 	Percent      float64 `json:"percent"`
 }
 
-// DiffSummary contains statistics about coverage changes between base and current.
-type DiffSummary struct {
-	NewlyCoveredLines   int     `json:"newlyCoveredLines"`
-	NewlyUncoveredLines int     `json:"newlyUncoveredLines"`
-	DeltaPercent        float64 `json:"deltaPercent"`
-	BasePercent         float64 `json:"basePercent"`
+// ResolveAttempt is one location tried while looking for the source of a
+// profile entry.
+type ResolveAttempt struct {
+	Method string `json:"method"` // resolution strategy, e.g. "module", "vendor", "go-list"
+	Path   string `json:"path"`   // location that was tried
+	Error  string `json:"error,omitempty"`
+}
+
+// Diagnostic records how a coverage profile entry was resolved to a source
+// file, or why it was skipped.
+type Diagnostic struct {
+	FileName string           `json:"fileName"`       // file name as written in the profile
+	Path     string           `json:"path,omitempty"` // report path, when resolved
+	Method   string           `json:"method,omitempty"`
+	Skipped  bool             `json:"skipped"`
+	Reason   string           `json:"reason,omitempty"` // why the entry was skipped
+	Attempts []ResolveAttempt `json:"attempts"`
 }
 
 // CoverageData is the complete data structure passed to the HTML template.
 type CoverageData struct {
-	Files       []FileData   `json:"files"`
-	Tree        *TreeNode    `json:"tree"`
-	Summary     Summary      `json:"summary"`
-	DiffSummary *DiffSummary `json:"diffSummary,omitempty"`
-	IsDiffMode  bool         `json:"isDiffMode"`
+	Mode         string        `json:"mode,omitempty"` // profile mode: "set", "count" or "atomic"
+	Files        []FileData    `json:"files"`
+	Tree         *TreeNode     `json:"tree"`
+	Summary      Summary       `json:"summary"`
+	DiffSummary  *DiffSummary  `json:"diffSummary,omitempty"`
+	IsDiffMode   bool          `json:"isDiffMode"`
+	DeletedFiles []DeletedFile `json:"deletedFiles,omitempty"` // diff mode only
+	Patch        *PatchSummary `json:"patch,omitempty"`        // coverage of the changed lines, -ref only
+	Diagnostics  []Diagnostic  `json:"diagnostics,omitempty"`  // one per profile entry
+	Gates        []GateResult  `json:"gates,omitempty"`        // coverage gates checked for the build
+}
+
+// GateResult is the outcome of a coverage gate such as -min-coverage.
+type GateResult struct {
+	Name    string `json:"name"`           // flag of the gate, e.g. "min-coverage"
+	Path    string `json:"path,omitempty"` // directory checked by a -thresholds rule
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// SchemaVersion is the version of the Report JSON schema. It is bumped on
+// changes that older readers cannot handle.
+const SchemaVersion = 1
+
+// Report is the JSON document written by the json export format, holding
+// everything needed to render the other formats again, sources included.
+type Report struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Coverage      *CoverageData `json:"coverage"`
 }
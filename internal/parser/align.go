@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// maxAlignEdits bounds the edit distance alignLines searches, as Myers'
+// algorithm keeps a trace growing with its square. Lines of files differing
+// more are left unaligned.
+const maxAlignEdits = 2000
+
+// alignLines maps each line of curr to the line of base it is unchanged from,
+// or -1 for lines added or edited since base. Common leading and trailing
+// lines are matched directly and the rest with a Myers diff, then inserted
+// lines are moved to the best of their equivalent positions.
+func alignLines(base, curr []string) []int {
+	mapping := make([]int, len(curr))
+	for i := range mapping {
+		mapping[i] = -1
+	}
+
+	prefix := 0
+	for prefix < len(base) && prefix < len(curr) && base[prefix] == curr[prefix] {
+		mapping[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(curr)-prefix &&
+		base[len(base)-1-suffix] == curr[len(curr)-1-suffix] {
+		mapping[len(curr)-1-suffix] = len(base) - 1 - suffix
+		suffix++
+	}
+
+	for _, m := range myersMatches(base[prefix:len(base)-suffix], curr[prefix:len(curr)-suffix]) {
+		mapping[prefix+m[1]] = prefix + m[0]
+	}
+	slideInsertions(curr, mapping)
+	return mapping
+}
+
+// slideInsertions moves each run of unmatched lines of mapping up or down
+// when the lines around it allow, such as an appended function whose closing
+// brace may equally be matched with the closing brace of the function before
+// it. Like the git diff heuristics, the run is kept where it starts or ends
+// with a blank line, so that it holds whole declarations, and otherwise as far
+// down as it goes. Runs are not merged with their neighbours.
+func slideInsertions(curr []string, mapping []int) {
+	// slide moves the run [s, e) one line down, or up when down is false
+	slide := func(s, e int, down bool) {
+		if down {
+			mapping[s], mapping[e] = mapping[e], -1
+		} else {
+			mapping[e-1], mapping[s-1] = mapping[s-1], -1
+		}
+	}
+	score := func(s, e int) int {
+		n := 0
+		if strings.TrimSpace(curr[s]) == "" {
+			n++
+		}
+		if strings.TrimSpace(curr[e-1]) == "" {
+			n++
+		}
+		return n
+	}
+
+	for s := 0; s < len(mapping); {
+		if mapping[s] >= 0 {
+			s++
+			continue
+		}
+		e := s
+		for e < len(mapping) && mapping[e] < 0 {
+			e++
+		}
+
+		for s > 0 && mapping[s-1] >= 0 && (s == 1 || mapping[s-2] >= 0) && curr[s-1] == curr[e-1] {
+			slide(s, e, false)
+			s, e = s-1, e-1
+		}
+		best, bestScore := s, score(s, e)
+		for e < len(mapping) && (e+1 == len(mapping) || mapping[e+1] >= 0) && curr[s] == curr[e] {
+			slide(s, e, true)
+			s, e = s+1, e+1
+			if n := score(s, e); n >= bestScore {
+				best, bestScore = s, n
+			}
+		}
+		for s > best {
+			slide(s, e, false)
+			s, e = s-1, e-1
+		}
+		s = e
+	}
+}
+
+// myersMatches returns the pairs of indexes of the lines of a and b kept by a
+// shortest edit script, or nil when it is longer than maxAlignEdits.
+func myersMatches(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return nil
+	}
+	maxD := min(n+m, maxAlignEdits)
+
+	// v holds the furthest x reached on each diagonal k = x - y, trace the
+	// diagonals -d..d of v after each step d.
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return myersBacktrack(trace, n, m, d)
+			}
+		}
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+	return nil
+}
+
+// myersBacktrack walks the trace back from (n, m), which was reached at step
+// d, and returns the matched pairs, last first.
+func myersBacktrack(trace [][]int, n, m, d int) [][2]int {
+	var matches [][2]int
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1] // diagonals -(d-1)..d-1, at prev[k+d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			matches = append(matches, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		matches = append(matches, [2]int{x, y})
+	}
+	return matches
+}
+
+// changedHunks returns the ranges of lines of a mapping from alignLines that
+// have no base line.
+func changedHunks(mapping []int) []model.Hunk {
+	var hunks []model.Hunk
+	for i, baseIdx := range mapping {
+		if baseIdx >= 0 {
+			continue
+		}
+		if n := len(hunks); n > 0 && hunks[n-1].End == i {
+			hunks[n-1].End = i + 1
+			continue
+		}
+		hunks = append(hunks, model.Hunk{Start: i + 1, End: i + 1})
+	}
+	return hunks
+}
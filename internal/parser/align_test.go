@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestAlignLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, curr string
+		want       []int
+	}{
+		{"identical", "a b c", "a b c", []int{0, 1, 2}},
+		{"inserted at the top", "a b c", "x a b c", []int{-1, 0, 1, 2}},
+		{"deleted", "a b c d", "a d", []int{0, 3}},
+		{"edited", "a b c", "a B c", []int{0, -1, 2}},
+		{"moved", "a b c d", "c a b d", []int{-1, 0, 1, 3}},
+		{"new file", "", "a b", []int{-1, -1}},
+		{"emptied", "a b", "", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alignLines(strings.Fields(tt.base), strings.Fields(tt.curr))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAlignLinesAppendedFunction(t *testing.T) {
+	base := []string{
+		"package p",
+		"",
+		"func A(x int) int {",
+		"\treturn x",
+		"}",
+		"",
+		"func B() int {",
+		"\treturn 0",
+		"}",
+	}
+	curr := []string{
+		"package p",
+		"",
+		"func A(x int) int {",
+		"\treturn x + 1",
+		"}",
+		"",
+		"func B() int {",
+		"\treturn 0",
+		"}",
+		"",
+		"func C() int {",
+		"\treturn 1",
+		"}",
+	}
+
+	// The closing brace of B stays matched, C is new from its blank line
+	hunks := changedHunks(alignLines(base, curr))
+	want := []model.Hunk{{Start: 4, End: 4}, {Start: 10, End: 13}}
+	if len(hunks) != len(want) {
+		t.Fatalf("expected hunks %v, got %v", want, hunks)
+	}
+	for i := range want {
+		if hunks[i] != want[i] {
+			t.Fatalf("expected hunks %v, got %v", want, hunks)
+		}
+	}
+}
+
+// TestAlignLinesLongest checks that random edits are aligned on a longest
+// common subsequence, with increasing and matching line pairs.
+func TestAlignLinesLongest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	words := []string{"a", "b", "c", "d", "}", ""}
+	random := func() []string {
+		lines := make([]string, rng.IntN(40))
+		for i := range lines {
+			lines[i] = words[rng.IntN(len(words))]
+		}
+		return lines
+	}
+
+	for range 500 {
+		base, curr := random(), random()
+		mapping := alignLines(base, curr)
+
+		matched, last := 0, -1
+		for i, j := range mapping {
+			if j < 0 {
+				continue
+			}
+			if j <= last || base[j] != curr[i] {
+				t.Fatalf("invalid alignment of %q and %q: %v", base, curr, mapping)
+			}
+			last = j
+			matched++
+		}
+		if want := lcsLength(base, curr); matched != want {
+			t.Fatalf("aligned %d lines of %q and %q, want %d", matched, base, curr, want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i][j] = dp[i-1][j-1] + 1
+			} else {
+				dp[i][j] = max(dp[i-1][j], dp[i][j-1])
+			}
+		}
+	}
+	return dp[len(a)][len(b)]
+}
+
+func TestAlignLinesTooManyEdits(t *testing.T) {
+	base := make([]string, maxAlignEdits)
+	curr := make([]string, maxAlignEdits+1)
+	for i := range base {
+		base[i] = "old"
+	}
+	for i := range curr {
+		curr[i] = "new"
+	}
+	for i, j := range alignLines(base, curr) {
+		if j != -1 {
+			t.Fatalf("line %d: expected no alignment past the edit limit, got %d", i, j)
+		}
+	}
+}
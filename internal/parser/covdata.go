@@ -0,0 +1,374 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/cover"
+)
+
+// readProfile parses a text coverage profile, or a binary coverage data
+// directory as written by programs built with "go build -cover" to GOCOVERDIR.
+func readProfile(path string) ([]*cover.Profile, error) {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		return readCoverDir(path)
+	}
+	return cover.ParseProfiles(path)
+}
+
+// Layout of the coverage data files, version 1 as of Go 1.20, see the
+// internal/coverage package of the Go distribution.
+var (
+	covMetaMagic    = []byte{0x00, 0x63, 0x76, 0x6d} // "\x00cvm"
+	covCounterMagic = []byte{0x00, 0x63, 0x77, 0x6d} // "\x00cwm"
+)
+
+const (
+	covVersion = 1
+
+	covMetaFileHeaderSize    = 56 // magic, version, length, entries, hash, string table, mode, granularity, padding
+	covMetaPackageHeaderSize = 44 // length, name, path, module, hash, padding, files, functions
+	covCounterHeaderSize     = 32 // magic, version, meta hash, flavor, big endian, padding
+	covFooterSize            = 16 // magic, padding, segments, padding
+
+	covPerFunc = 2 // counter granularity with a single counter per function
+
+	covCounterRaw  = 1 // counters as 32-bit integers
+	covCounterULEB = 2 // counters as ULEB128 varints
+)
+
+// coverMeta is the decoded content of a covmeta file: the functions of each
+// package, in the order counter files refer to them.
+type coverMeta struct {
+	name     string
+	mode     string
+	perFunc  bool
+	packages [][]coverFunc
+}
+
+// coverFunc is a function with its coverable units, in counter order.
+type coverFunc struct {
+	file  string
+	units []cover.ProfileBlock
+}
+
+// readCoverDir decodes the covmeta and covcounters files of a coverage data
+// directory. Each covmeta file describes the code of a binary and the
+// covcounters files named after its hash hold the counters of each of its
+// runs, which are merged like the profiles given to -profile.
+func readCoverDir(dir string) ([]*cover.Profile, error) {
+	metaFiles, err := filepath.Glob(filepath.Join(dir, "covmeta.*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(metaFiles) == 0 {
+		return nil, fmt.Errorf("no coverage data (covmeta files) found in directory")
+	}
+
+	metas := make(map[string]*coverMeta, len(metaFiles))
+	var names []string
+	var sets [][]*cover.Profile
+	for _, path := range metaFiles {
+		content, err := os.ReadFile(path) //nolint:gosec // path is from the -profile directory
+		if err != nil {
+			return nil, err
+		}
+		hash, meta, err := decodeCoverMeta(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		meta.name = filepath.Base(path)
+		metas[hash] = meta
+
+		// Functions that never ran have no counters, they are listed with a
+		// zero count
+		names = append(names, meta.name)
+		sets = append(sets, meta.profiles(nil))
+	}
+
+	counterFiles, err := filepath.Glob(filepath.Join(dir, "covcounters.*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range counterFiles {
+		content, err := os.ReadFile(path) //nolint:gosec // path is from the -profile directory
+		if err != nil {
+			return nil, err
+		}
+		hash, counters, err := decodeCoverCounters(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		meta, ok := metas[hash]
+		if !ok {
+			return nil, fmt.Errorf("%s: no covmeta file for its meta hash %s", filepath.Base(path), hash)
+		}
+		names = append(names, filepath.Base(path))
+		sets = append(sets, meta.profiles(counters))
+	}
+	return mergeProfiles(names, sets)
+}
+
+// profiles returns the blocks of the functions with counters, one profile per
+// source file, or of every function with a zero count when counters is nil.
+// counters maps package and function indices to the counters of a run.
+func (m *coverMeta) profiles(counters map[[2]uint32][]uint32) []*cover.Profile {
+	byFile := make(map[string]*cover.Profile)
+	var result []*cover.Profile
+	for pkg, funcs := range m.packages {
+		for fn, f := range funcs {
+			values, ran := counters[[2]uint32{uint32(pkg), uint32(fn)}] //nolint:gosec // indices of decoded slices
+			if counters != nil && !ran {
+				continue
+			}
+			p, ok := byFile[f.file]
+			if !ok {
+				p = &cover.Profile{FileName: f.file, Mode: m.mode}
+				byFile[f.file] = p
+				result = append(result, p)
+			}
+			for i, unit := range f.units {
+				switch {
+				case m.perFunc && len(values) > 0:
+					unit.Count = int(values[0])
+				case i < len(values):
+					unit.Count = int(values[i])
+				}
+				p.Blocks = append(p.Blocks, unit)
+			}
+		}
+	}
+	return result
+}
+
+// decodeCoverMeta decodes a covmeta file, returning its hash in hex.
+func decodeCoverMeta(content []byte) (string, *coverMeta, error) {
+	r := &covReader{b: content}
+	if !bytes.Equal(r.bytes(4), covMetaMagic) {
+		return "", nil, fmt.Errorf("not a coverage meta-data file")
+	}
+	if version := r.u32(); version != covVersion {
+		return "", nil, fmt.Errorf("unsupported coverage meta-data version %d", version)
+	}
+	r.u64() // total length
+	entries := r.u64()
+	hash := hex.EncodeToString(r.bytes(16))
+	r.u32() // file string table offset
+	r.u32() // file string table length
+	mode := int(r.u8())
+	granularity := r.u8()
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	meta := &coverMeta{perFunc: granularity == covPerFunc}
+	switch mode {
+	case 1:
+		meta.mode = "set"
+	case 2:
+		meta.mode = "count"
+	case 3:
+		meta.mode = "atomic"
+	default:
+		return "", nil, fmt.Errorf("unsupported coverage counter mode %d", mode)
+	}
+
+	if entries > uint64(len(content)) {
+		return "", nil, fmt.Errorf("malformed coverage meta-data: %d packages", entries)
+	}
+	r.off = covMetaFileHeaderSize
+	offsets := make([]uint64, entries)
+	for i := range offsets {
+		offsets[i] = r.u64()
+	}
+	lengths := make([]uint64, entries)
+	for i := range lengths {
+		lengths[i] = r.u64()
+	}
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	for i := range offsets {
+		end := offsets[i] + lengths[i]
+		if end < offsets[i] || end > uint64(len(content)) {
+			return "", nil, fmt.Errorf("malformed coverage meta-data: package %d out of the file", i)
+		}
+		funcs, err := decodeCoverPackage(content[offsets[i]:end])
+		if err != nil {
+			return "", nil, fmt.Errorf("package %d: %w", i, err)
+		}
+		meta.packages = append(meta.packages, funcs)
+	}
+	return hash, meta, nil
+}
+
+// decodeCoverPackage decodes the functions of a package in a covmeta file.
+func decodeCoverPackage(content []byte) ([]coverFunc, error) {
+	r := &covReader{b: content}
+	r.off = covMetaPackageHeaderSize - 4
+	numFuncs := int(r.u32())
+	if r.err != nil || numFuncs > len(content)/4 {
+		return nil, fmt.Errorf("malformed package header")
+	}
+	offsets := make([]uint32, numFuncs)
+	for i := range offsets {
+		offsets[i] = r.u32()
+	}
+	strs := r.strings()
+
+	funcs := make([]coverFunc, numFuncs)
+	for i, off := range offsets {
+		r.off = int(off)
+		numUnits := r.uleb()
+		r.uleb() // function name
+		file := r.uleb()
+		if r.err != nil || file >= uint64(len(strs)) || numUnits > uint64(len(content)) {
+			return nil, fmt.Errorf("malformed function %d", i)
+		}
+		f := coverFunc{file: strs[file], units: make([]cover.ProfileBlock, numUnits)}
+		for j := range f.units {
+			f.units[j] = cover.ProfileBlock{
+				StartLine: int(r.uleb()), //nolint:gosec // line and column numbers
+				StartCol:  int(r.uleb()), //nolint:gosec // line and column numbers
+				EndLine:   int(r.uleb()), //nolint:gosec // line and column numbers
+				EndCol:    int(r.uleb()), //nolint:gosec // line and column numbers
+				NumStmt:   int(r.uleb()), //nolint:gosec // statement count
+			}
+		}
+		r.uleb() // function literal flag
+		funcs[i] = f
+	}
+	return funcs, r.err
+}
+
+// decodeCoverCounters decodes a covcounters file, returning the hash of its
+// covmeta file in hex and the counters of each function that ran, by package
+// and function index.
+func decodeCoverCounters(content []byte) (string, map[[2]uint32][]uint32, error) {
+	r := &covReader{b: content}
+	if !bytes.Equal(r.bytes(4), covCounterMagic) {
+		return "", nil, fmt.Errorf("not a coverage counter data file")
+	}
+	if version := r.u32(); version != covVersion {
+		return "", nil, fmt.Errorf("unsupported coverage counter data version %d", version)
+	}
+	hash := hex.EncodeToString(r.bytes(16))
+	flavor := r.u8()
+	bigEndian := r.u8() != 0
+
+	// The footer at the end holds the number of segments, each segment
+	// being followed by a footer of its own
+	if len(content) < covCounterHeaderSize+covFooterSize {
+		return "", nil, fmt.Errorf("truncated coverage counter data file")
+	}
+	footer := &covReader{b: content, off: len(content) - covFooterSize}
+	if !bytes.Equal(footer.bytes(4), covCounterMagic) {
+		return "", nil, fmt.Errorf("malformed coverage counter data footer")
+	}
+	footer.u32()
+	segments := footer.u32()
+
+	value := r.uleb32
+	switch {
+	case flavor == covCounterRaw && bigEndian:
+		value = func() uint32 { return binary.BigEndian.Uint32(r.bytes(4)) }
+	case flavor == covCounterRaw:
+		value = r.u32
+	case flavor != covCounterULEB:
+		return "", nil, fmt.Errorf("unsupported coverage counter flavor %d", flavor)
+	}
+
+	counters := make(map[[2]uint32][]uint32)
+	r.off = covCounterHeaderSize
+	for range segments {
+		numFuncs := r.u64()
+		strTabLen := r.u32()
+		argsLen := r.u32()
+		r.off += int(strTabLen) + int(argsLen)
+		// Counters are 4-byte aligned
+		r.off = (r.off + 3) &^ 3
+		for i := uint64(0); i < numFuncs && r.err == nil; i++ {
+			n := value()
+			for n == 0 && r.err == nil {
+				n = value()
+			}
+			key := [2]uint32{value(), value()}
+			if uint64(n) > uint64(len(content)) {
+				return "", nil, fmt.Errorf("malformed counters for function %d of package %d", key[1], key[0])
+			}
+			values := make([]uint32, n)
+			for j := range values {
+				values[j] = value()
+			}
+			counters[key] = values
+		}
+		r.off += covFooterSize
+		if r.err != nil {
+			return "", nil, r.err
+		}
+	}
+	return hash, counters, nil
+}
+
+// covReader reads the little endian integers and ULEB128 varints of coverage
+// data files, recording the first read past the end.
+type covReader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *covReader) bytes(n int) []byte {
+	if r.err != nil || r.off < 0 || n > len(r.b)-r.off {
+		r.err = fmt.Errorf("truncated coverage data at offset %d", r.off)
+		return make([]byte, n)
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *covReader) u8() uint8 { return r.bytes(1)[0] }
+
+func (r *covReader) u32() uint32 { return binary.LittleEndian.Uint32(r.bytes(4)) }
+
+func (r *covReader) u64() uint64 { return binary.LittleEndian.Uint64(r.bytes(8)) }
+
+func (r *covReader) uleb() uint64 {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b := r.u8()
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 || r.err != nil {
+			return value
+		}
+	}
+	r.err = fmt.Errorf("malformed varint at offset %d", r.off)
+	return value
+}
+
+func (r *covReader) uleb32() uint32 { return uint32(r.uleb()) } //nolint:gosec // counters are 32-bit
+
+// strings reads a string table: the number of strings, then the length and
+// bytes of each.
+func (r *covReader) strings() []string {
+	n := r.uleb()
+	if n > uint64(len(r.b)) {
+		r.err = fmt.Errorf("malformed string table at offset %d", r.off)
+		return nil
+	}
+	strs := make([]string, n)
+	for i := range strs {
+		size := r.uleb()
+		if size > uint64(len(r.b)) {
+			r.err = fmt.Errorf("malformed string table at offset %d", r.off)
+			return nil
+		}
+		strs[i] = string(r.bytes(int(size)))
+	}
+	return strs
+}
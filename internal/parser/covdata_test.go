@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCoverDir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary with -cover")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod": "module example.com/app\n",
+		"main.go": `package main
+
+import ("os"; "example.com/app/lib")
+
+func main() {
+	if len(os.Args) > 1 {
+		println("args")
+	}
+	println("done")
+	f := func() { println(lib.Add(1, 2)) }
+	f()
+}
+`,
+		"lib/lib.go": `package lib
+
+func Add(a, b int) int {
+	if a > 10 {
+		return 0
+	}
+	return a + b
+}
+
+func Unused() {}
+`,
+	})
+
+	build := exec.Command("go", "build", "-cover", "-covermode=count", "-coverpkg=./...", "-o", "app", ".")
+	build.Dir = tmpDir
+	build.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v: %s", err, output)
+	}
+
+	coverDir := filepath.Join(tmpDir, "covdata")
+	if err := os.Mkdir(coverDir, 0o755); err != nil { //nolint:gosec // test directory
+		t.Fatalf("failed to create coverage directory: %v", err)
+	}
+	for range 2 {
+		run := exec.Command(filepath.Join(tmpDir, "app"))
+		run.Env = append(os.Environ(), "GOCOVERDIR="+coverDir)
+		if output, err := run.CombinedOutput(); err != nil {
+			t.Fatalf("running app failed: %v: %s", err, output)
+		}
+	}
+
+	// The directory decodes to the profile written by go tool covdata
+	textfmt := filepath.Join(tmpDir, "textfmt.out")
+	convert := exec.Command("go", "tool", "covdata", "textfmt", "-i="+coverDir, "-o="+textfmt)
+	if output, err := convert.CombinedOutput(); err != nil {
+		t.Fatalf("go tool covdata failed: %v: %s", err, output)
+	}
+	want, err := readProfile(textfmt)
+	if err != nil {
+		t.Fatalf("reading the textfmt profile failed: %v", err)
+	}
+	got, err := readProfile(coverDir)
+	if err != nil {
+		t.Fatalf("readProfile failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded profiles differ from go tool covdata:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	data, err := Parse(coverDir, tmpDir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if data.Mode != "count" {
+		t.Errorf("expected mode 'count', got %q", data.Mode)
+	}
+	if len(data.Files) != 2 || data.Files[1].Path != "main.go" {
+		t.Fatalf("expected lib/lib.go and main.go, got %+v", data.Files)
+	}
+	file := data.Files[1]
+	if file.Counts[5] != 2 {
+		t.Errorf("expected the if statement to run twice, got %d", file.Counts[5])
+	}
+	if file.Coverage[6] != 1 {
+		t.Errorf("expected line 7 to be uncovered, got %d", file.Coverage[6])
+	}
+}
+
+func TestParseCoverDirEmpty(t *testing.T) {
+	_, err := Parse(t.TempDir(), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "no coverage data") {
+		t.Errorf("expected an error for a directory without coverage data, got %v", err)
+	}
+}
+
+func TestParseCoverDirMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"not meta-data", map[string]string{"covmeta.aa": "mode: set\n"}, "not a coverage meta-data file"},
+		{"newer version", map[string]string{"covmeta.aa": "\x00cvm\x02\x00\x00\x00"}, "unsupported coverage meta-data version 2"},
+		{"truncated", map[string]string{"covmeta.aa": "\x00cvm\x01\x00\x00\x00"}, "truncated coverage data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			_, err := readCoverDir(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
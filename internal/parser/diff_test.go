@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/chmouel/go-better-html-coverage/internal/model"
@@ -13,7 +14,9 @@ func TestComputeDiff(t *testing.T) {
 		current            *model.CoverageData
 		wantNewlyCovered   int
 		wantNewlyUncovered int
-		wantDiffStates     []int // expected diff states for first file
+		wantNewlyPartial   int
+		wantNewCode        [2]int // new code covered and uncovered lines
+		wantDiffStates     []int  // expected diff states for first file
 	}{
 		{
 			name: "newly covered line",
@@ -99,9 +102,8 @@ func TestComputeDiff(t *testing.T) {
 				},
 				Summary: model.Summary{TotalLines: 2, CoveredLines: 1, Percent: 50},
 			},
-			wantNewlyCovered:   1,
-			wantNewlyUncovered: 1,
-			wantDiffStates:     []int{DiffStateNewlyCovered, DiffStateNewlyUncovered},
+			wantNewCode:    [2]int{1, 1},
+			wantDiffStates: []int{DiffStateNewCodeCovered, DiffStateNewCodeUncovered},
 		},
 		{
 			name: "mixed changes",
@@ -121,6 +123,84 @@ func TestComputeDiff(t *testing.T) {
 			wantNewlyUncovered: 1, // line c: 2->1
 			wantDiffStates:     []int{DiffStateUnchangedCovered, DiffStateNewlyCovered, DiffStateNewlyUncovered, DiffStateNoChange},
 		},
+		{
+			name: "statement added to an existing line",
+			base: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "b", "c"}, Coverage: []int{2, 0, 2}},
+				},
+			},
+			current: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "b", "c"}, Coverage: []int{1, 1, 2}},
+				},
+			},
+			wantNewlyUncovered: 1, // line a: 2->1
+			wantNewCode:        [2]int{0, 1},
+			wantDiffStates:     []int{DiffStateNewlyUncovered, DiffStateNewCodeUncovered, DiffStateUnchangedCovered},
+		},
+		{
+			name: "inserted lines shift the base lines",
+			base: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "b", "c", "d"}, Coverage: []int{0, 2, 1, 2}},
+				},
+			},
+			current: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "new", "b", "c", "d"}, Coverage: []int{0, 2, 2, 1, 1}},
+				},
+			},
+			wantNewlyUncovered: 1,            // line d: 2->1
+			wantNewCode:        [2]int{1, 0}, // the new line
+			wantDiffStates:     []int{DiffStateNoChange, DiffStateNewCodeCovered, DiffStateUnchangedCovered, DiffStateUnchangedUncovered, DiffStateNewlyUncovered},
+		},
+		{
+			name: "covered line now partially covered",
+			base: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a"}, Coverage: []int{2}},
+				},
+			},
+			current: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a"}, Coverage: []int{3}},
+				},
+			},
+			wantNewlyPartial: 1,
+			wantDiffStates:   []int{DiffStateNewlyPartial},
+		},
+		{
+			name: "uncovered line now partially covered",
+			base: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a"}, Coverage: []int{1}},
+				},
+			},
+			current: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a"}, Coverage: []int{3}},
+				},
+			},
+			wantNewlyCovered: 1,
+			wantDiffStates:   []int{DiffStateNewlyCovered},
+		},
+		{
+			name: "partial lines",
+			base: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "b", "c"}, Coverage: []int{3, 3, 3}},
+				},
+			},
+			current: &model.CoverageData{
+				Files: []model.FileData{
+					{ID: 0, Path: "foo.go", Lines: []string{"a", "b", "c"}, Coverage: []int{1, 2, 3}},
+				},
+			},
+			wantNewlyCovered:   1, // line b: 3->2
+			wantNewlyUncovered: 1, // line a: 3->1
+			wantDiffStates:     []int{DiffStateNewlyUncovered, DiffStateNewlyCovered, DiffStateUnchangedUncovered},
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,6 +225,16 @@ func TestComputeDiff(t *testing.T) {
 					result.DiffSummary.NewlyUncoveredLines, tt.wantNewlyUncovered)
 			}
 
+			if result.DiffSummary.NewlyPartialLines != tt.wantNewlyPartial {
+				t.Errorf("NewlyPartialLines = %d, want %d",
+					result.DiffSummary.NewlyPartialLines, tt.wantNewlyPartial)
+			}
+
+			newCode := [2]int{result.DiffSummary.NewCodeCoveredLines, result.DiffSummary.NewCodeUncoveredLines}
+			if newCode != tt.wantNewCode {
+				t.Errorf("new code covered and uncovered lines = %v, want %v", newCode, tt.wantNewCode)
+			}
+
 			if len(result.Files) > 0 && tt.wantDiffStates != nil {
 				file := result.Files[0]
 				if len(file.DiffState) != len(tt.wantDiffStates) {
@@ -186,3 +276,127 @@ func TestComputeDiff_DeltaPercent(t *testing.T) {
 		t.Errorf("BasePercent = %f, want 50.0", result.DiffSummary.BasePercent)
 	}
 }
+
+func TestComputeDiff_Hunks(t *testing.T) {
+	base := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "foo.go", Lines: []string{"a", "b", "c"}, Coverage: []int{2, 2, 2}},
+		},
+	}
+	current := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "foo.go", Lines: []string{"a", "B", "c", "d"}, Coverage: []int{2, 2, 2, 2}},
+			{Path: "new.go", Lines: []string{"x", "y"}, Coverage: []int{0, 2}},
+		},
+	}
+
+	result := ComputeDiff(base, current)
+	want := [][]model.Hunk{
+		{{Start: 2, End: 2}, {Start: 4, End: 4}},
+		{{Start: 1, End: 2}},
+	}
+	for i, file := range result.Files {
+		if len(file.Hunks) != len(want[i]) {
+			t.Errorf("%s: expected hunks %v, got %v", file.Path, want[i], file.Hunks)
+			continue
+		}
+		for j := range want[i] {
+			if file.Hunks[j] != want[i][j] {
+				t.Errorf("%s: expected hunks %v, got %v", file.Path, want[i], file.Hunks)
+			}
+		}
+	}
+}
+
+func TestComputeDiff_FileDiff(t *testing.T) {
+	base := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "a.go", Lines: []string{"a", "b", "c"}, Coverage: []int{2, 2, 1}},
+			{Path: "b.go", Lines: []string{"x"}, Coverage: []int{1}},
+		},
+	}
+	current := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "a.go", Lines: []string{"a", "b", "c", "d"}, Coverage: []int{1, 2, 2, 1}},
+			{Path: "b.go", Lines: []string{"x"}, Coverage: []int{2}},
+			{Path: "new.go", Lines: []string{"n"}, Coverage: []int{2}},
+		},
+	}
+
+	result := ComputeDiff(base, current)
+	want := []model.FileDiff{
+		{NewlyCoveredLines: 1, NewlyUncoveredLines: 1, NewCodeUncoveredLines: 1},
+		{NewlyCoveredLines: 1},
+		{NewCodeCoveredLines: 1},
+	}
+	var sum model.DiffSummary
+	for i, file := range result.Files {
+		if file.Diff == nil || *file.Diff != want[i] {
+			t.Errorf("%s: expected diff %+v, got %+v", file.Path, want[i], file.Diff)
+			continue
+		}
+		sum.Add(*file.Diff)
+	}
+	sum.BasePercent, sum.DeltaPercent = result.DiffSummary.BasePercent, result.DiffSummary.DeltaPercent
+	if sum != *result.DiffSummary {
+		t.Errorf("expected the file diffs to add up to %+v, got %+v", *result.DiffSummary, sum)
+	}
+	if result.Files[2].Base != nil {
+		t.Errorf("expected no base totals for a new file, got %+v", result.Files[2].Base)
+	}
+}
+
+func TestComputeDiff_MissingBaseSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":  "module testmod\n",
+		"main.go": "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		// sign.go was deleted since the base profile was taken
+		"base.out": `mode: set
+testmod/main.go:3.13,5.2 1 1
+testmod/sign.go:4.2,4.11 1 1
+testmod/sign.go:5.3,6.1 1 1
+testmod/sign.go:6.9,6.19 1 0
+testmod/sign.go:7.3,8.1 1 0
+testmod/sign.go:9.2,9.10 1 0
+`,
+		"coverage.out": `mode: set
+testmod/main.go:3.13,5.2 1 1
+`,
+	})
+	current, err := ParseFiles([]string{filepath.Join(tmpDir, "coverage.out")}, tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("parsing current profile: %v", err)
+	}
+
+	basePath := filepath.Join(tmpDir, "base.out")
+	base, err := ParseFiles([]string{basePath}, tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("parsing base profile: %v", err)
+	}
+	if len(base.DeletedFiles) != 0 {
+		t.Errorf("missing sources should only be kept with KeepMissing, got %+v", base.DeletedFiles)
+	}
+
+	base, err = ParseFiles([]string{basePath}, tmpDir, Options{KeepMissing: true})
+	if err != nil {
+		t.Fatalf("parsing base profile: %v", err)
+	}
+	result := ComputeDiff(base, current)
+
+	want := []model.DeletedFile{{
+		Path:   "sign.go",
+		Totals: model.Totals{TotalLines: 6, CoveredLines: 2, TotalStatements: 5, CoveredStatements: 2},
+	}}
+	if len(result.DeletedFiles) != 1 || result.DeletedFiles[0] != want[0] {
+		t.Fatalf("expected deleted files %+v, got %+v", want, result.DeletedFiles)
+	}
+	s := result.DiffSummary
+	if s.DeletedFiles != 1 || s.DeletedCoveredLines != 2 {
+		t.Errorf("unexpected deleted counts %+v", s)
+	}
+	// 5 of the 9 base lines were covered
+	if diff := s.BasePercent - 500.0/9; diff > 0.001 || diff < -0.001 {
+		t.Errorf("expected base percent %.2f, got %.2f", 500.0/9, s.BasePercent)
+	}
+}
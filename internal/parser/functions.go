@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"golang.org/x/tools/cover"
+)
+
+// findFunctions parses the source of a Go file and returns every function and
+// method declaration with the statements the profile blocks attribute to it,
+// using the same rules as "go tool cover -func". Files that fail to parse
+// return no functions.
+func findFunctions(filename string, lines []string, blocks []cover.ProfileBlock) []model.Function {
+	fset := token.NewFileSet()
+	src := strings.Join(lines, "\n")
+	f, err := goparser.ParseFile(fset, filename, src, goparser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var functions []model.Function
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			// Skip assembly function declarations.
+			continue
+		}
+		start := fset.Position(fn.Pos())
+		end := fset.Position(fn.End())
+
+		function := model.Function{
+			Name:      fn.Name.Name,
+			StartLine: start.Line,
+			EndLine:   end.Line,
+		}
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			function.Receiver = receiverType(fn.Recv.List[0].Type)
+		}
+
+		for _, b := range blocks {
+			if b.StartLine > end.Line || (b.StartLine == end.Line && b.StartCol >= end.Column) {
+				break
+			}
+			if b.EndLine < start.Line || (b.EndLine == start.Line && b.EndCol <= start.Column) {
+				continue
+			}
+			function.Statements += b.NumStmt
+			if b.Count > 0 {
+				function.Covered += b.NumStmt
+			}
+		}
+		if function.Statements > 0 {
+			function.Percent = float64(function.Covered) / float64(function.Statements) * 100
+		}
+		functions = append(functions, function)
+	}
+	return functions
+}
+
+// receiverType renders a receiver type expression without type parameters,
+// e.g. "*Parser" for "func (p *Parser[T]) ...".
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.ParenExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestFindFunctions(t *testing.T) {
+	src := `package main
+
+type Server[T any] struct{}
+
+func (s *Server[T]) Start() {
+	println("start")
+}
+
+func helper() int {
+	return 1
+}
+
+func unused() {
+	println("unused")
+}
+`
+	lines := strings.Split(src, "\n")
+	blocks := []cover.ProfileBlock{
+		{StartLine: 5, StartCol: 29, EndLine: 7, EndCol: 2, NumStmt: 1, Count: 3},
+		{StartLine: 9, StartCol: 19, EndLine: 11, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 13, StartCol: 15, EndLine: 15, EndCol: 2, NumStmt: 1, Count: 0},
+	}
+
+	functions := findFunctions("main.go", lines, blocks)
+	if len(functions) != 3 {
+		t.Fatalf("expected 3 functions, got %d", len(functions))
+	}
+
+	start := functions[0]
+	if start.Name != "Start" || start.Receiver != "*Server" {
+		t.Errorf("unexpected method: %+v", start)
+	}
+	if start.DisplayName() != "(*Server).Start" {
+		t.Errorf("unexpected display name %q", start.DisplayName())
+	}
+	if start.StartLine != 5 || start.EndLine != 7 {
+		t.Errorf("unexpected line range %d-%d", start.StartLine, start.EndLine)
+	}
+	if start.Statements != 1 || start.Covered != 1 || start.Percent != 100 {
+		t.Errorf("unexpected coverage for Start: %+v", start)
+	}
+
+	unused := functions[2]
+	if unused.Name != "unused" || unused.Receiver != "" {
+		t.Errorf("unexpected function: %+v", unused)
+	}
+	if unused.Statements != 1 || unused.Covered != 0 || unused.Percent != 0 {
+		t.Errorf("unexpected coverage for unused: %+v", unused)
+	}
+}
+
+func TestFindFunctionsInvalidSource(t *testing.T) {
+	functions := findFunctions("bad.go", []string{"not go code {"}, nil)
+	if functions != nil {
+		t.Errorf("expected no functions for invalid source, got %+v", functions)
+	}
+}
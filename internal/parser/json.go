@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// ReadJSON reads coverage data from a report written by the json export
+// format, so that it can be rendered again without the profiles or the
+// sources. The tree and the summary are recomputed, using metric when it is
+// not empty and the metric of the report otherwise.
+func ReadJSON(path, metric string) (*model.CoverageData, error) {
+	content, err := os.ReadFile(path) //nolint:gosec // path is from the command line
+	if err != nil {
+		return nil, err
+	}
+
+	var report model.Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	switch {
+	case report.SchemaVersion == 0 || report.Coverage == nil:
+		return nil, fmt.Errorf("%s is not a coverage JSON export", path)
+	case report.SchemaVersion > model.SchemaVersion:
+		return nil, fmt.Errorf("%s uses schema version %d, this version reads up to %d",
+			path, report.SchemaVersion, model.SchemaVersion)
+	}
+
+	data := report.Coverage
+	if metric == "" {
+		metric = data.Summary.Metric
+	}
+	if data.Summary.Metric, err = checkMetric(metric); err != nil {
+		return nil, err
+	}
+	for _, file := range data.Files {
+		if len(file.Coverage) != len(file.Lines) {
+			return nil, fmt.Errorf("%s: %s has %d lines but coverage for %d", path, file.Path, len(file.Lines), len(file.Coverage))
+		}
+		// The optional per-line slices are indexed by line as well
+		if len(file.Counts) != 0 && len(file.Counts) != len(file.Lines) {
+			return nil, fmt.Errorf("%s: %s has %d lines but counts for %d", path, file.Path, len(file.Lines), len(file.Counts))
+		}
+		if len(file.DiffState) != 0 && len(file.DiffState) != len(file.Lines) {
+			return nil, fmt.Errorf("%s: %s has %d lines but diff states for %d", path, file.Path, len(file.Lines), len(file.DiffState))
+		}
+		for _, h := range file.Hunks {
+			if h.Start <= 0 || h.End < h.Start || h.End > len(file.Lines) {
+				return nil, fmt.Errorf("%s: %s has %d lines but a hunk of lines %d-%d", path, file.Path, len(file.Lines), h.Start, h.End)
+			}
+		}
+	}
+	return withFiles(data, data.Files), nil
+}
+
+// IsJSONReport reports whether path is a file holding a JSON object, as
+// written by the json export format, rather than a text coverage profile.
+func IsJSONReport(path string) bool {
+	f, err := os.Open(path) //nolint:gosec // path is from the command line
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	var head [512]byte
+	n, _ := f.Read(head[:])
+	trimmed := bytes.TrimSpace(head[:n])
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func writeReport(t *testing.T, report any) string {
+	t.Helper()
+	content, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("failed to marshal report: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "coverage.json")
+	if err := os.WriteFile(path, content, 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to write report: %v", err)
+	}
+	return path
+}
+
+func TestReadJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":     "module example.com/app\n",
+		"main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"lib/lib.go": "package lib\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"coverage.out": `mode: set
+example.com/app/main.go:3.13,5.2 1 1
+example.com/app/lib/lib.go:3.25,5.2 1 0
+`,
+	})
+	data, err := ParseFiles([]string{filepath.Join(tmpDir, "coverage.out")}, tmpDir, Options{Metric: model.MetricStatements})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	path := writeReport(t, model.Report{SchemaVersion: model.SchemaVersion, Coverage: data})
+
+	// The sources are embedded, the workspace is not needed anymore
+	if err := os.RemoveAll(tmpDir); err != nil {
+		t.Fatalf("failed to remove workspace: %v", err)
+	}
+
+	read, err := ReadJSON(path, "")
+	if err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+	if len(read.Files) != 2 || read.Files[1].Lines[3] != "\tprintln(\"hello\")" {
+		t.Fatalf("unexpected files: %+v", read.Files)
+	}
+	if read.Summary != data.Summary {
+		t.Errorf("expected summary %+v, got %+v", data.Summary, read.Summary)
+	}
+	if read.Tree == nil || len(read.Tree.Children) != 2 {
+		t.Errorf("expected the tree to be rebuilt, got %+v", read.Tree)
+	}
+
+	read, err = ReadJSON(path, model.MetricLines)
+	if err != nil {
+		t.Fatalf("ReadJSON failed: %v", err)
+	}
+	if read.Summary.Metric != model.MetricLines || read.Summary.Percent != read.Summary.LinePercent {
+		t.Errorf("expected the summary to use the line metric, got %+v", read.Summary)
+	}
+}
+
+func TestReadJSONInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		report any
+		want   string
+	}{
+		{"not an export", map[string]any{"files": []any{}}, "not a coverage JSON export"},
+		{"newer schema", model.Report{SchemaVersion: model.SchemaVersion + 1, Coverage: &model.CoverageData{}}, "schema version"},
+		{
+			"inconsistent file",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a"}}},
+			}},
+			"coverage for 0",
+		},
+		{
+			"truncated counts",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a", ""}, Coverage: []int{0, 0}, Counts: []int{0}}},
+			}},
+			"counts for 1",
+		},
+		{
+			"truncated diff states",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a"}, Coverage: []int{0}, DiffState: []int{0, 0}}},
+			}},
+			"diff states for 2",
+		},
+		{
+			"hunk past the end",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a"}, Coverage: []int{0}, Hunks: []model.Hunk{{Start: 1, End: 2}}}},
+			}},
+			"hunk of lines 1-2",
+		},
+		{
+			"reversed hunk",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a", ""}, Coverage: []int{0, 0}, Hunks: []model.Hunk{{Start: 2, End: 1}}}},
+			}},
+			"hunk of lines 2-1",
+		},
+		{
+			"hunk before the start",
+			model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{
+				Files: []model.FileData{{Path: "a.go", Lines: []string{"package a"}, Coverage: []int{0}, Hunks: []model.Hunk{{Start: 0, End: 1}}}},
+			}},
+			"hunk of lines 0-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadJSON(writeReport(t, tt.report), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestIsJSONReport(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "coverage.out")
+	if err := os.WriteFile(profile, []byte("mode: set\n"), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	report := writeReport(t, model.Report{SchemaVersion: model.SchemaVersion, Coverage: &model.CoverageData{}})
+
+	tests := map[string]bool{
+		report:                            true,
+		profile:                           false,
+		dir:                               false,
+		filepath.Join(dir, "missing.out"): false,
+	}
+	for path, want := range tests {
+		if got := IsJSONReport(path); got != want {
+			t.Errorf("IsJSONReport(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
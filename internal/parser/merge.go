@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// readProfiles parses every profile or coverage data directory in paths and
+// merges them into a single set of profiles, one per source file.
+func readProfiles(paths []string) ([]*cover.Profile, error) {
+	sets := make([][]*cover.Profile, 0, len(paths))
+	for _, path := range paths {
+		profiles, err := readProfile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sets = append(sets, profiles)
+	}
+	return mergeProfiles(paths, sets)
+}
+
+// mergeProfiles combines the profiles read from several coverage files.
+// Blocks covering the same span of the same file are merged: counts are
+// summed in count and atomic mode and OR-ed in set mode. All inputs must use
+// the same mode.
+func mergeProfiles(names []string, sets [][]*cover.Profile) ([]*cover.Profile, error) {
+	mode, modeSource := "", ""
+	byFile := make(map[string]*cover.Profile)
+	var order []string
+
+	for i, profiles := range sets {
+		for _, p := range profiles {
+			switch {
+			case mode == "":
+				mode, modeSource = p.Mode, names[i]
+			case p.Mode != mode:
+				return nil, fmt.Errorf("cannot merge profiles with different modes: %s uses %q but %s uses %q",
+					modeSource, mode, names[i], p.Mode)
+			}
+
+			merged, ok := byFile[p.FileName]
+			if !ok {
+				merged = &cover.Profile{FileName: p.FileName, Mode: p.Mode}
+				byFile[p.FileName] = merged
+				order = append(order, p.FileName)
+			}
+			merged.Blocks = append(merged.Blocks, p.Blocks...)
+		}
+	}
+
+	sort.Strings(order)
+	result := make([]*cover.Profile, 0, len(order))
+	for _, name := range order {
+		p := byFile[name]
+		blocks, err := mergeBlocks(p.Blocks, mode)
+		if err != nil {
+			return nil, fmt.Errorf("merging blocks for %s: %w", name, err)
+		}
+		p.Blocks = blocks
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// mergeBlocks sorts blocks by position and folds blocks with identical spans
+// together.
+func mergeBlocks(blocks []cover.ProfileBlock, mode string) ([]cover.ProfileBlock, error) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		a, b := blocks[i], blocks[j]
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+
+	merged := make([]cover.ProfileBlock, 0, len(blocks))
+	for _, b := range blocks {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if b.StartLine == last.StartLine && b.StartCol == last.StartCol &&
+				b.EndLine == last.EndLine && b.EndCol == last.EndCol {
+				if b.NumStmt != last.NumStmt {
+					return nil, fmt.Errorf("inconsistent statement count at %d.%d,%d.%d: %d vs %d",
+						b.StartLine, b.StartCol, b.EndLine, b.EndCol, last.NumStmt, b.NumStmt)
+				}
+				if mode == "set" {
+					last.Count |= b.Count
+				} else {
+					last.Count += b.Count
+				}
+				continue
+			}
+		}
+		merged = append(merged, b)
+	}
+	return merged, nil
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func writeProfile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	return path
+}
+
+func TestReadProfilesMergeCount(t *testing.T) {
+	tmpDir := t.TempDir()
+	unit := writeProfile(t, tmpDir, "unit.out", `mode: count
+testmod/a.go:3.13,5.2 1 2
+testmod/a.go:7.14,9.2 1 0
+`)
+	integration := writeProfile(t, tmpDir, "integration.out", `mode: count
+testmod/a.go:3.13,5.2 1 5
+testmod/a.go:7.14,9.2 1 1
+testmod/b.go:1.1,2.2 3 4
+`)
+
+	profiles, err := readProfiles([]string{unit, integration})
+	if err != nil {
+		t.Fatalf("readProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(profiles))
+	}
+
+	a := profiles[0]
+	if a.FileName != "testmod/a.go" {
+		t.Fatalf("expected testmod/a.go first, got %s", a.FileName)
+	}
+	if len(a.Blocks) != 2 {
+		t.Fatalf("expected 2 merged blocks, got %d", len(a.Blocks))
+	}
+	if a.Blocks[0].Count != 7 {
+		t.Errorf("expected summed count 7, got %d", a.Blocks[0].Count)
+	}
+	if a.Blocks[1].Count != 1 {
+		t.Errorf("expected summed count 1, got %d", a.Blocks[1].Count)
+	}
+	if profiles[1].Blocks[0].NumStmt != 3 {
+		t.Errorf("expected b.go block to be kept, got %+v", profiles[1].Blocks)
+	}
+}
+
+func TestMergeBlocksSetMode(t *testing.T) {
+	blocks := []cover.ProfileBlock{
+		{StartLine: 7, StartCol: 1, EndLine: 8, EndCol: 2, NumStmt: 1, Count: 0},
+		{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 7, StartCol: 1, EndLine: 8, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 1, Count: 1},
+	}
+
+	merged, err := mergeBlocks(blocks, "set")
+	if err != nil {
+		t.Fatalf("mergeBlocks failed: %v", err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(merged))
+	}
+	for i, b := range merged {
+		if b.Count != 1 {
+			t.Errorf("block %d: expected count 1 in set mode, got %d", i, b.Count)
+		}
+	}
+	if merged[0].StartLine != 3 {
+		t.Errorf("expected blocks sorted by position, got %+v", merged)
+	}
+}
+
+func TestMergeBlocksInconsistentStatements(t *testing.T) {
+	blocks := []cover.ProfileBlock{
+		{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 1, Count: 1},
+		{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 2, NumStmt: 2, Count: 1},
+	}
+	if _, err := mergeBlocks(blocks, "count"); err == nil {
+		t.Error("expected error for inconsistent statement counts")
+	}
+}
+
+func TestReadProfilesModeMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	set := writeProfile(t, tmpDir, "set.out", "mode: set\ntestmod/a.go:3.13,5.2 1 1\n")
+	count := writeProfile(t, tmpDir, "count.out", "mode: count\ntestmod/a.go:3.13,5.2 1 3\n")
+
+	_, err := readProfiles([]string{set, count})
+	if err == nil {
+		t.Fatal("expected error for mode mismatch")
+	}
+	if !strings.Contains(err.Error(), "set.out") || !strings.Contains(err.Error(), "count.out") {
+		t.Errorf("error should name both profiles, got: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// module is a Go module that is part of the source tree.
+type module struct {
+	Path string // module path from go.mod
+	Dir  string // slash-separated directory relative to the source root, "." for the root
+}
+
+// findModules returns the modules making up the source tree: the modules
+// listed by the use directives of go.work, if any, and every go.mod found in
+// the tree. Modules are sorted longest path first so that nested
+// modules take precedence over the modules containing them.
+func findModules(fsys fs.FS) ([]module, error) {
+	dirs := make(map[string]struct{})
+
+	workDirs, err := parseGoWork(fsys, "go.work")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading go.work: %w", err)
+	}
+	for _, dir := range workDirs {
+		dirs[dir] = struct{}{}
+	}
+
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && skipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs[path.Dir(p)] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]module, 0, len(dirs))
+	for dir := range dirs {
+		modPath, err := detectModulePath(fsys, dir)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", dir, err)
+		}
+		modules = append(modules, module{Path: modPath, Dir: dir})
+	}
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no go.mod or go.work found in the source tree")
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		if len(modules[i].Path) != len(modules[j].Path) {
+			return len(modules[i].Path) > len(modules[j].Path)
+		}
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules, nil
+}
+
+// skipDir reports whether a directory is ignored by the go command when
+// looking for packages.
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || name == "node_modules" ||
+		strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// parseGoWork returns the directories listed by the use directives of a
+// go.work file, cleaned and relative to the directory holding it.
+func parseGoWork(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var dirs []string
+	inUseBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inUseBlock && line == ")":
+			inUseBlock = false
+			continue
+		case inUseBlock:
+		case strings.HasPrefix(line, "use") && strings.TrimSpace(line[len("use"):]) == "(":
+			inUseBlock = true
+			continue
+		default:
+			rest, found := strings.CutPrefix(line, "use ")
+			if !found {
+				continue
+			}
+			line = strings.TrimSpace(rest)
+		}
+
+		if line == "" {
+			continue
+		}
+		dirs = append(dirs, path.Clean(strings.Trim(line, "\"`")))
+	}
+	return dirs, scanner.Err()
+}
+
+// modulePath maps the import-path style file name found in a coverage profile
+// to a slash-separated path relative to the source root, using the module
+// owning it. It returns false if no module of the source tree owns the file.
+func modulePath(modules []module, fileName string) (string, bool) {
+	for _, m := range modules {
+		rest, found := strings.CutPrefix(fileName, m.Path+"/")
+		if !found {
+			continue
+		}
+		return path.Join(m.Dir, rest), true
+	}
+	return "", false
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // test directory
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec // test file
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+}
+
+func TestFindModulesNested(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":                      "module example.com/repo\n",
+		"tools/go.mod":                "module example.com/repo/tools\n",
+		"testdata/fixture/go.mod":     "module example.com/fixture\n",
+		"vendor/example.com/x/go.mod": "module example.com/x\n",
+	})
+
+	modules, err := findModules(os.DirFS(tmpDir))
+	if err != nil {
+		t.Fatalf("findModules failed: %v", err)
+	}
+	if len(modules) != 2 {
+		t.Fatalf("expected 2 modules, got %+v", modules)
+	}
+	if modules[0].Path != "example.com/repo/tools" || modules[0].Dir != "tools" {
+		t.Errorf("expected nested module first, got %+v", modules[0])
+	}
+
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{"example.com/repo/main.go", "main.go"},
+		{"example.com/repo/tools/cmd/gen.go", "tools/cmd/gen.go"},
+		{"example.com/repo/toolsbox/x.go", "toolsbox/x.go"},
+	}
+	for _, tt := range tests {
+		got, ok := modulePath(modules, tt.fileName)
+		if !ok || got != tt.want {
+			t.Errorf("modulePath(%q) = %q, %v; want %q", tt.fileName, got, ok, tt.want)
+		}
+	}
+	if _, ok := modulePath(modules, "github.com/other/dep/x.go"); ok {
+		t.Error("expected no module for a dependency")
+	}
+}
+
+func TestParseGoWork(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.work": `go 1.22
+
+// shared libraries
+use (
+	./services/api
+	./libs/common // common code
+)
+
+use ./tools
+`,
+	})
+
+	dirs, err := parseGoWork(os.DirFS(tmpDir), "go.work")
+	if err != nil {
+		t.Fatalf("parseGoWork failed: %v", err)
+	}
+	want := []string{"services/api", "libs/common", "tools"}
+	if len(dirs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, dirs)
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Errorf("dir %d: expected %s, got %s", i, want[i], dirs[i])
+		}
+	}
+}
+
+func TestParseWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.work":                "go 1.22\n\nuse (\n\t./api\n\t./lib\n)\n",
+		"api/go.mod":             "module example.com/api\n",
+		"api/server.go":          "package api\n\nfunc Serve() {\n\tprintln(\"serve\")\n}\n",
+		"lib/go.mod":             "module example.com/lib\n",
+		"lib/strings/strings.go": "package strings\n\nfunc Upper() {\n\tprintln(\"upper\")\n}\n",
+		"coverage.out": `mode: set
+example.com/api/server.go:3.14,5.2 1 1
+example.com/lib/strings/strings.go:3.14,5.2 1 0
+`,
+	})
+
+	data, err := Parse(filepath.Join(tmpDir, "coverage.out"), tmpDir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(data.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(data.Files))
+	}
+	if data.Files[0].Path != "api/server.go" {
+		t.Errorf("expected api/server.go, got %s", data.Files[0].Path)
+	}
+	if data.Files[1].Path != "lib/strings/strings.go" {
+		t.Errorf("expected lib/strings/strings.go, got %s", data.Files[1].Path)
+	}
+}
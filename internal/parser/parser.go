@@ -3,93 +3,144 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"regexp"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/chmouel/go-better-html-coverage/internal/model"
 	"golang.org/x/tools/cover"
 )
 
+// Options configures how coverage profiles are parsed.
+type Options struct {
+	// ResolveDeps locates the sources of files that are not part of the
+	// source tree, such as dependencies instrumented with -coverpkg, in
+	// vendor directories and with "go list".
+	ResolveDeps bool
+
+	// Sources holds the source tree the profile paths are resolved against,
+	// for example a git revision or an archive. When nil, the sources are
+	// read from srcRoot on disk.
+	Sources fs.FS
+
+	// Metric selects the coverage percentage reported in the summary,
+	// model.MetricLines (the default) or model.MetricStatements.
+	Metric string
+
+	// IncludeUntested adds the Go files of the source tree that are missing
+	// from the profiles, such as packages without tests, with all their
+	// statements uncovered.
+	IncludeUntested bool
+
+	// KeepMissing keeps the entries of the source tree whose source file is
+	// missing as DeletedFiles, with their totals computed from the blocks.
+	// This is meant for a base profile parsed against the current tree,
+	// where the files deleted since the base have no source anymore.
+	KeepMissing bool
+}
+
 // Parse reads a coverage profile and source files, returning CoverageData.
 func Parse(profilePath, srcRoot string) (*model.CoverageData, error) {
-	profiles, err := cover.ParseProfiles(profilePath)
+	return ParseFiles([]string{profilePath}, srcRoot, Options{})
+}
+
+// ParseFiles reads and merges several coverage profiles, for example from
+// sharded test runs, and returns CoverageData for the combined result.
+func ParseFiles(profilePaths []string, srcRoot string, opts Options) (*model.CoverageData, error) {
+	metric, err := checkMetric(opts.Metric)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := readProfiles(profilePaths)
 	if err != nil {
 		return nil, fmt.Errorf("parsing coverage profile: %w", err)
 	}
 
-	// Detect module path from go.mod
-	modPath, err := detectModulePath(srcRoot)
+	sources := opts.Sources
+	if sources == nil {
+		sources = os.DirFS(srcRoot)
+	}
+
+	// Find the modules of the source tree, from go.work and go.mod files
+	modules, err := findModules(sources)
 	if err != nil {
 		return nil, fmt.Errorf("detecting module path: %w", err)
 	}
 
-	var files []model.FileData
-	totalLines := 0
-	coveredLines := 0
-
-	for i, p := range profiles {
-		// Convert module path to file path
-		relPath := strings.TrimPrefix(p.FileName, modPath+"/")
-		if relPath == p.FileName {
-			// File might be in the root of the module
-			relPath = p.FileName
-		}
+	res, err := newResolver(srcRoot, sources, modules, profiles, opts.ResolveDeps)
+	if err != nil {
+		return nil, err
+	}
 
-		fullPath := filepath.Join(srcRoot, relPath)
-		lines, err := readLines(fullPath)
-		if err != nil {
-			// Try stripping module prefix differently
-			parts := strings.SplitN(p.FileName, "/", 4)
-			if len(parts) >= 4 {
-				altPath := filepath.Join(srcRoot, parts[3])
-				lines, err = readLines(altPath)
-				if err != nil {
-					continue // Skip files we can't read
-				}
-				relPath = parts[3]
-			} else {
-				continue
+	var files []model.FileData
+	var missing []model.DeletedFile
+	diagnostics := make([]model.Diagnostic, 0, len(profiles))
+	mode := ""
+
+	for _, p := range profiles {
+		mode = p.Mode
+
+		relPath, lines, diag := res.resolve(p.FileName)
+		diagnostics = append(diagnostics, diag)
+		if diag.Skipped {
+			if relPath, owned := modulePath(modules, p.FileName); owned && opts.KeepMissing {
+				missing = append(missing, model.DeletedFile{Path: relPath, Totals: blockTotals(p.Blocks)})
 			}
+			continue // Skip files we can't read
 		}
 
-		coverage := computeLineCoverage(lines, p.Blocks)
 		fd := model.FileData{
-			ID:       i,
-			Path:     relPath,
-			Lines:    lines,
-			Coverage: coverage,
+			ID:        len(files),
+			Path:      relPath,
+			Lines:     lines,
+			Coverage:  computeLineCoverage(lines, p.Blocks),
+			Counts:    computeLineCounts(lines, p.Blocks),
+			Blocks:    convertBlocks(p.Blocks),
+			Functions: findFunctions(relPath, lines, p.Blocks),
 		}
 		files = append(files, fd)
+	}
 
-		// Compute stats
-		for _, c := range coverage {
-			if c > 0 {
-				totalLines++
-				if c == 2 {
-					coveredLines++
-				}
+	if opts.IncludeUntested {
+		known := make(map[string]struct{}, len(files))
+		for _, f := range files {
+			known[f.Path] = struct{}{}
+		}
+		untested, err := findUntested(sources, modules, known)
+		if err != nil {
+			return nil, fmt.Errorf("looking for untested files: %w", err)
+		}
+		for _, relPath := range untested {
+			lines, err := readLines(sources, relPath)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", relPath, err)
 			}
+			blocks := untestedBlocks(relPath, lines)
+			files = append(files, model.FileData{
+				ID:           len(files),
+				Path:         relPath,
+				Lines:        lines,
+				Coverage:     computeLineCoverage(lines, blocks),
+				Counts:       computeLineCounts(lines, blocks),
+				Blocks:       convertBlocks(blocks),
+				Functions:    findFunctions(relPath, lines, blocks),
+				NotInProfile: true,
+			})
 		}
 	}
 
-	tree := buildTree(files)
-
-	percent := 0.0
-	if totalLines > 0 {
-		percent = float64(coveredLines) / float64(totalLines) * 100
-	}
-
 	return &model.CoverageData{
-		Files: files,
-		Tree:  tree,
-		Summary: model.Summary{
-			TotalLines:   totalLines,
-			CoveredLines: coveredLines,
-			Percent:      percent,
-		},
+		Mode:         mode,
+		Files:        files,
+		Tree:         buildTree(files),
+		Summary:      summarize(files, metric),
+		DeletedFiles: missing,
+		Diagnostics:  diagnostics,
 	}, nil
 }
 
@@ -100,43 +151,14 @@ func FilterByPaths(data *model.CoverageData, allowed map[string]struct{}) *model
 	}
 
 	filteredFiles := make([]model.FileData, 0, len(data.Files))
-	totalLines := 0
-	coveredLines := 0
-
 	for _, file := range data.Files {
 		if _, ok := allowed[file.Path]; !ok {
 			continue
 		}
-		file.ID = len(filteredFiles)
 		filteredFiles = append(filteredFiles, file)
-
-		for _, c := range file.Coverage {
-			if c > 0 {
-				totalLines++
-				if c == 2 {
-					coveredLines++
-				}
-			}
-		}
-	}
-
-	tree := buildTree(filteredFiles)
-	percent := 0.0
-	if totalLines > 0 {
-		percent = float64(coveredLines) / float64(totalLines) * 100
 	}
 
-	return &model.CoverageData{
-		Files: filteredFiles,
-		Tree:  tree,
-		Summary: model.Summary{
-			TotalLines:   totalLines,
-			CoveredLines: coveredLines,
-			Percent:      percent,
-		},
-		DiffSummary: data.DiffSummary,
-		IsDiffMode:  data.IsDiffMode,
-	}
+	return withFiles(data, filteredFiles)
 }
 
 // FilterByRegex filters coverage data to exclude files matching any of the provided regex patterns.
@@ -146,56 +168,89 @@ func FilterByRegex(data *model.CoverageData, patterns []*regexp.Regexp) *model.C
 	}
 
 	filteredFiles := make([]model.FileData, 0, len(data.Files))
-	totalLines := 0
-	coveredLines := 0
-
 	for _, file := range data.Files {
-		excluded := false
-		for _, pattern := range patterns {
-			if pattern.MatchString(file.Path) {
-				excluded = true
-				break
-			}
-		}
-		if excluded {
-			continue
+		if !matchesAny(patterns, file.Path) {
+			filteredFiles = append(filteredFiles, file)
 		}
+	}
 
-		file.ID = len(filteredFiles)
-		filteredFiles = append(filteredFiles, file)
+	// Deleted files are excluded alike, so that they leave the base
+	result := *data
+	result.DeletedFiles = nil
+	for _, file := range data.DeletedFiles {
+		if !matchesAny(patterns, file.Path) {
+			result.DeletedFiles = append(result.DeletedFiles, file)
+		}
+	}
+	return withFiles(&result, filteredFiles)
+}
 
-		for _, c := range file.Coverage {
-			if c > 0 {
-				totalLines++
-				if c == 2 {
-					coveredLines++
-				}
-			}
+// matchesAny reports whether s matches any of patterns.
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
 		}
 	}
+	return false
+}
 
-	tree := buildTree(filteredFiles)
-	percent := 0.0
-	if totalLines > 0 {
-		percent = float64(coveredLines) / float64(totalLines) * 100
+// withFiles returns a copy of data holding only files, with file IDs, the
+// tree, the summary, and the diff and patch summaries, when set, recomputed.
+func withFiles(data *model.CoverageData, files []model.FileData) *model.CoverageData {
+	for i := range files {
+		files[i].ID = i
 	}
 
-	return &model.CoverageData{
-		Files: filteredFiles,
-		Tree:  tree,
-		Summary: model.Summary{
-			TotalLines:   totalLines,
-			CoveredLines: coveredLines,
-			Percent:      percent,
-		},
-		DiffSummary: data.DiffSummary,
-		IsDiffMode:  data.IsDiffMode,
+	result := *data
+	result.Files = files
+	result.Tree = buildTree(files)
+	result.Summary = summarize(files, data.Summary.Metric)
+	if data.DiffSummary != nil {
+		result.DiffSummary = summarizeDiff(files, data.DeletedFiles, data.Summary.Metric)
+	}
+	if data.Patch != nil {
+		result.Patch = summarizePatch(files)
+	}
+	return &result
+}
+
+// checkMetric validates a coverage metric name, defaulting to lines.
+func checkMetric(metric string) (string, error) {
+	switch metric {
+	case "":
+		return model.MetricLines, nil
+	case model.MetricLines, model.MetricStatements:
+		return metric, nil
+	default:
+		return "", fmt.Errorf("unknown coverage metric %q, expected %q or %q", metric, model.MetricLines, model.MetricStatements)
+	}
+}
+
+// summarize computes line and statement coverage statistics over files, with
+// Percent taken from metric.
+func summarize(files []model.FileData, metric string) model.Summary {
+	var totals model.Totals
+	for _, file := range files {
+		totals.Add(file.Totals())
+	}
+
+	summary := model.Summary{
+		TotalLines:        totals.TotalLines,
+		CoveredLines:      totals.CoveredLines,
+		PartialLines:      totals.PartialLines,
+		TotalStatements:   totals.TotalStatements,
+		CoveredStatements: totals.CoveredStatements,
+		LinePercent:       totals.Percent(model.MetricLines),
+		StatementPercent:  totals.Percent(model.MetricStatements),
+		Metric:            metric,
 	}
+	summary.Percent = totals.Percent(metric)
+	return summary
 }
 
-func detectModulePath(srcRoot string) (string, error) {
-	goModPath := filepath.Join(srcRoot, "go.mod")
-	f, err := os.Open(goModPath) //nolint:gosec // path is from srcRoot argument
+func detectModulePath(fsys fs.FS, dir string) (string, error) {
+	f, err := fsys.Open(path.Join(dir, "go.mod"))
 	if err != nil {
 		return "", err
 	}
@@ -211,8 +266,8 @@ func detectModulePath(srcRoot string) (string, error) {
 	return "", fmt.Errorf("module directive not found in go.mod")
 }
 
-func readLines(path string) ([]string, error) {
-	f, err := os.Open(path) //nolint:gosec // path is from coverage profile
+func readLines(fsys fs.FS, name string) ([]string, error) {
+	f, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
@@ -229,24 +284,150 @@ func readLines(path string) ([]string, error) {
 func computeLineCoverage(lines []string, blocks []cover.ProfileBlock) []int {
 	coverage := make([]int, len(lines))
 
+	// Track, per line, whether covered and uncovered code was seen. Blocks
+	// that only contribute whitespace or braces to a line (such as the rest
+	// of an "if cond {" line before the body starts) are "weak": they only
+	// decide the line state when nothing else is on it.
+	type lineHits struct {
+		covered, uncovered         bool
+		weakCovered, weakUncovered bool
+	}
+	hits := make([]lineHits, len(lines))
+
 	for _, b := range blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
 		for line := b.StartLine; line <= b.EndLine && line <= len(lines); line++ {
 			idx := line - 1 // Convert to 0-indexed
 			if idx < 0 || idx >= len(coverage) {
 				continue
 			}
-			if b.NumStmt > 0 {
-				if b.Count > 0 {
-					coverage[idx] = 2 // covered
-				} else if coverage[idx] == 0 {
-					coverage[idx] = 1 // uncovered (but has statements)
-				}
+			h := &hits[idx]
+			significant := hasCode(blockSegment(lines[idx], line, b))
+			switch {
+			case significant && b.Count > 0:
+				h.covered = true
+			case significant:
+				h.uncovered = true
+			case b.Count > 0:
+				h.weakCovered = true
+			default:
+				h.weakUncovered = true
 			}
 		}
 	}
+
+	for idx, h := range hits {
+		switch {
+		case h.covered && h.uncovered:
+			coverage[idx] = 3 // partially covered
+		case h.covered:
+			coverage[idx] = 2 // covered
+		case h.uncovered:
+			coverage[idx] = 1 // uncovered
+		case h.weakCovered:
+			coverage[idx] = 2
+		case h.weakUncovered:
+			coverage[idx] = 1
+		}
+	}
 	return coverage
 }
 
+// blockSegment returns the part of line (1-based lineNum) that block b spans.
+func blockSegment(line string, lineNum int, b cover.ProfileBlock) string {
+	start, end := 0, len(line)
+	if lineNum == b.StartLine {
+		start = max(b.StartCol-1, 0)
+	}
+	if lineNum == b.EndLine {
+		end = min(max(b.EndCol-1, 0), len(line))
+	}
+	if start >= end {
+		return ""
+	}
+	return line[start:end]
+}
+
+// hasCode reports whether s contains anything besides whitespace and braces.
+func hasCode(s string) bool {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '{' || r == '}'
+	}) != ""
+}
+
+// blockTotals computes the totals of a file whose source is missing from its
+// blocks alone. Without the source every line a block spans counts as code,
+// so lines holding only a closing brace may be counted as well.
+func blockTotals(blocks []cover.ProfileBlock) model.Totals {
+	var numLines, width int
+	for _, b := range blocks {
+		numLines = max(numLines, b.EndLine)
+		width = max(width, b.StartCol, b.EndCol)
+	}
+
+	// Stand-in lines without braces, so that any part of a line spanned by a
+	// block counts as code
+	filler := strings.Repeat("x", width)
+	lines := make([]string, numLines)
+	for i := range lines {
+		lines[i] = filler
+	}
+	file := model.FileData{Coverage: computeLineCoverage(lines, blocks), Blocks: convertBlocks(blocks)}
+	return file.Totals()
+}
+
+// computeLineCounts returns, for each line, the highest execution count of
+// the blocks with statements that span it. Weak blocks, as in
+// computeLineCoverage, only count on lines without any other block, so a line
+// has a count exactly when it is covered or partially covered.
+func computeLineCounts(lines []string, blocks []cover.ProfileBlock) []int {
+	counts := make([]int, len(lines))
+	weakCounts := make([]int, len(lines))
+	significant := make([]bool, len(lines))
+
+	for _, b := range blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+		for line := b.StartLine; line <= b.EndLine && line <= len(lines); line++ {
+			idx := line - 1
+			if idx < 0 {
+				continue
+			}
+			if hasCode(blockSegment(lines[idx], line, b)) {
+				significant[idx] = true
+				counts[idx] = max(counts[idx], b.Count)
+			} else {
+				weakCounts[idx] = max(weakCounts[idx], b.Count)
+			}
+		}
+	}
+
+	for idx := range counts {
+		if !significant[idx] {
+			counts[idx] = weakCounts[idx]
+		}
+	}
+	return counts
+}
+
+func convertBlocks(blocks []cover.ProfileBlock) []model.Block {
+	result := make([]model.Block, 0, len(blocks))
+	for _, b := range blocks {
+		result = append(result, model.Block{
+			StartLine: b.StartLine,
+			StartCol:  b.StartCol,
+			EndLine:   b.EndLine,
+			EndCol:    b.EndCol,
+			NumStmt:   b.NumStmt,
+			Count:     b.Count,
+		})
+	}
+	return result
+}
+
 func buildTree(files []model.FileData) *model.TreeNode {
 	root := &model.TreeNode{
 		Name:     ".",
@@ -318,18 +499,37 @@ func sortTree(node *model.TreeNode) {
 	}
 }
 
-// Diff state constants for line-by-line comparison.
+// Diff state constants for line-by-line comparison. Lines that had no
+// statement in the base, because they were added or edited or their file is
+// new, are new code, and only existing code can be newly covered or regress.
 const (
 	DiffStateNoChange           = 0 // no statement or no diff
-	DiffStateNewlyCovered       = 1 // was uncovered, now covered
-	DiffStateNewlyUncovered     = 2 // was covered, now uncovered (regression)
+	DiffStateNewlyCovered       = 1 // existing code, was uncovered, now covered
+	DiffStateNewlyUncovered     = 2 // existing code, was covered, now uncovered (regression)
 	DiffStateUnchangedCovered   = 3 // covered in both
 	DiffStateUnchangedUncovered = 4 // uncovered in both
+	DiffStateNewCodeCovered     = 5 // new code, covered
+	DiffStateNewCodeUncovered   = 6 // new code, uncovered
+	DiffStateNewlyPartial       = 7 // existing code, was covered, now partially covered
 )
 
 // ComputeDiff compares base and current coverage data and returns a new
-// CoverageData with diff state information for each line.
+// CoverageData with diff state information for each line. Renamed files are
+// detected by content similarity.
 func ComputeDiff(base, current *model.CoverageData) *model.CoverageData {
+	return ComputeDiffWithRenames(base, current, nil)
+}
+
+// ComputeDiffWithRenames is ComputeDiff with the renamed files given as a map
+// of current paths to base paths, such as found by git, or detected by content
+// similarity when renames is nil. A renamed file is compared with its base
+// file, and the base files missing from current are reported as deleted, as
+// are the base entries kept without their source (see Options.KeepMissing).
+func ComputeDiffWithRenames(base, current *model.CoverageData, renames map[string]string) *model.CoverageData {
+	if renames == nil {
+		renames = DetectRenames(base, current)
+	}
+
 	// Build map of base files by path
 	baseFileMap := make(map[string]model.FileData)
 	for _, f := range base.Files {
@@ -338,72 +538,97 @@ func ComputeDiff(base, current *model.CoverageData) *model.CoverageData {
 
 	// Process each file in current
 	var resultFiles []model.FileData
-	var newlyCoveredTotal, newlyUncoveredTotal int
+	compared := make(map[string]bool)
 
 	for i, currFile := range current.Files {
 		baseFile, inBase := baseFileMap[currFile.Path]
+		if basePath, renamed := renames[currFile.Path]; renamed && !inBase {
+			if baseFile, inBase = baseFileMap[basePath]; inBase {
+				currFile.BasePath = basePath
+			}
+		}
 
-		diffState := make([]int, len(currFile.Coverage))
-
+		// New files are new code: no line maps to the base
+		var baseCoverage []int
+		mapping := make([]int, len(currFile.Coverage))
+		for idx := range mapping {
+			mapping[idx] = -1
+		}
 		if inBase {
-			diffState, newlyCoveredTotal, newlyUncoveredTotal = computeLineDiff(
-				baseFile.Coverage, currFile.Coverage,
-				newlyCoveredTotal, newlyUncoveredTotal,
-			)
-		} else {
-			// New file not in base: mark all statements as newly covered/uncovered
-			for idx, cov := range currFile.Coverage {
-				switch cov {
-				case 2: // covered
-					diffState[idx] = DiffStateNewlyCovered
-					newlyCoveredTotal++
-				case 1: // uncovered
-					diffState[idx] = DiffStateNewlyUncovered
-					newlyUncoveredTotal++
-				default:
-					diffState[idx] = DiffStateNoChange
-				}
-			}
+			compared[baseFile.Path] = true
+			baseTotals := baseFile.Totals()
+			currFile.Base = &baseTotals
+			baseCoverage = baseFile.Coverage
+			mapping = alignLines(baseFile.Lines, currFile.Lines)
 		}
+		currFile.Hunks = changedHunks(mapping)
 
-		resultFiles = append(resultFiles, model.FileData{
-			ID:        i,
-			Path:      currFile.Path,
-			Lines:     currFile.Lines,
-			Coverage:  currFile.Coverage,
-			DiffState: diffState,
-		})
+		currFile.ID = i
+		var diff model.FileDiff
+		currFile.DiffState = computeLineDiff(baseCoverage, currFile.Coverage, mapping, &diff)
+		currFile.Diff = &diff
+		resultFiles = append(resultFiles, currFile)
 	}
 
-	tree := buildTree(resultFiles)
+	var deleted []model.DeletedFile
+	if !base.IsDiffMode {
+		deleted = append(deleted, base.DeletedFiles...)
+	}
+	for _, f := range base.Files {
+		if compared[f.Path] {
+			continue
+		}
+		deleted = append(deleted, model.DeletedFile{Path: f.Path, Totals: f.Totals()})
+	}
 
-	deltaPercent := current.Summary.Percent - base.Summary.Percent
+	result := *current
+	result.Files = resultFiles
+	result.Tree = buildTree(resultFiles)
+	result.DiffSummary = summarizeDiff(resultFiles, deleted, current.Summary.Metric)
+	result.DeletedFiles = deleted
+	result.IsDiffMode = true
+	return &result
+}
 
-	return &model.CoverageData{
-		Files:   resultFiles,
-		Tree:    tree,
-		Summary: current.Summary,
-		DiffSummary: &model.DiffSummary{
-			NewlyCoveredLines:   newlyCoveredTotal,
-			NewlyUncoveredLines: newlyUncoveredTotal,
-			DeltaPercent:        deltaPercent,
-			BasePercent:         base.Summary.Percent,
-		},
-		IsDiffMode: true,
+// summarizeDiff adds up the line changes of files, and computes the coverage
+// change of metric from their base totals. The base includes the deleted
+// files, as it did in the base profile.
+func summarizeDiff(files []model.FileData, deleted []model.DeletedFile, metric string) *model.DiffSummary {
+	summary := &model.DiffSummary{}
+	var base, current model.Totals
+	for _, f := range files {
+		current.Add(f.Totals())
+		if f.Base != nil {
+			base.Add(*f.Base)
+		}
+		if f.Diff != nil {
+			summary.Add(*f.Diff)
+		}
+		if f.BasePath != "" {
+			summary.RenamedFiles++
+		}
+	}
+	for _, f := range deleted {
+		base.Add(f.Totals)
+		summary.DeletedFiles++
+		summary.DeletedCoveredLines += f.Totals.CoveredLines
 	}
+	summary.BasePercent = base.Percent(metric)
+	summary.DeltaPercent = current.Percent(metric) - summary.BasePercent
+	return summary
 }
 
-// computeLineDiff compares base and current coverage arrays line by line.
-func computeLineDiff(baseCov, currCov []int, newlyCovered, newlyUncovered int) ([]int, int, int) {
-	maxLen := len(currCov)
-	diffState := make([]int, maxLen)
+// computeLineDiff compares the coverage of each current line with the base
+// line it maps to, and counts the changes in diff. Lines added or edited
+// since the base map to no base line.
+func computeLineDiff(baseCov, currCov, mapping []int, diff *model.FileDiff) []int {
+	diffState := make([]int, len(currCov))
 
-	for idx := 0; idx < maxLen; idx++ {
+	for idx, currVal := range currCov {
 		var baseVal int
-		if idx < len(baseCov) {
-			baseVal = baseCov[idx]
+		if idx < len(mapping) && mapping[idx] >= 0 && mapping[idx] < len(baseCov) {
+			baseVal = baseCov[mapping[idx]]
 		}
-		currVal := currCov[idx]
 
 		// No statement in current
 		if currVal == 0 {
@@ -411,25 +636,32 @@ func computeLineDiff(baseCov, currCov []int, newlyCovered, newlyUncovered int) (
 			continue
 		}
 
-		// Determine diff state
-		baseCovered := baseVal == 2
-		currCovered := currVal == 2
-
+		// Determine diff state. Partial lines rank between uncovered and
+		// covered ones: only a line left without coverage is a regression.
 		switch {
-		case !baseCovered && currCovered:
-			// Was uncovered (or no statement), now covered
-			diffState[idx] = DiffStateNewlyCovered
-			newlyCovered++
-		case baseCovered && !currCovered:
-			// Was covered, now uncovered (regression)
-			diffState[idx] = DiffStateNewlyUncovered
-			newlyUncovered++
-		case currCovered:
+		case baseVal == 0 && currVal == 2:
+			diffState[idx] = DiffStateNewCodeCovered
+			diff.NewCodeCoveredLines++
+		case baseVal == 0:
+			diffState[idx] = DiffStateNewCodeUncovered
+			diff.NewCodeUncoveredLines++
+		case currVal == baseVal && currVal == 2:
 			diffState[idx] = DiffStateUnchangedCovered
-		default:
+		case currVal == baseVal:
 			diffState[idx] = DiffStateUnchangedUncovered
+		case currVal == 1:
+			// Was covered at least partially, now uncovered (regression)
+			diffState[idx] = DiffStateNewlyUncovered
+			diff.NewlyUncoveredLines++
+		case baseVal == 2:
+			diffState[idx] = DiffStateNewlyPartial
+			diff.NewlyPartialLines++
+		default:
+			// Was uncovered or partially covered, now covered more
+			diffState[idx] = DiffStateNewlyCovered
+			diff.NewlyCoveredLines++
 		}
 	}
 
-	return diffState, newlyCovered, newlyUncovered
+	return diffState
 }
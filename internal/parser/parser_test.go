@@ -73,6 +73,110 @@ func TestComputeLineCoverageOutOfBounds(t *testing.T) {
 	}
 }
 
+func TestComputeLineCoveragePartial(t *testing.T) {
+	lines := []string{
+		"func f(err error) error {",
+		"\tif err != nil { return err }",
+		"\tif err == nil {",
+		"\t\treturn nil",
+		"\t}",
+		"\treturn err",
+		"}",
+	}
+
+	// Blocks as emitted by the cover tool for the function above, where
+	// err was always nil.
+	blocks := []cover.ProfileBlock{
+		{StartLine: 1, StartCol: 25, EndLine: 2, EndCol: 16, NumStmt: 1, Count: 1},
+		{StartLine: 2, StartCol: 16, EndLine: 2, EndCol: 30, NumStmt: 1, Count: 0},
+		{StartLine: 3, StartCol: 2, EndLine: 3, EndCol: 17, NumStmt: 1, Count: 1},
+		{StartLine: 3, StartCol: 17, EndLine: 5, EndCol: 3, NumStmt: 1, Count: 1},
+		{StartLine: 6, StartCol: 2, EndLine: 6, EndCol: 12, NumStmt: 1, Count: 0},
+	}
+
+	coverage := computeLineCoverage(lines, blocks)
+
+	want := []int{2, 3, 2, 2, 2, 1, 0}
+	for i, w := range want {
+		if coverage[i] != w {
+			t.Errorf("line %d: expected %d, got %d", i+1, w, coverage[i])
+		}
+	}
+}
+
+func TestBlockSegment(t *testing.T) {
+	line := "\tif err != nil { return err }"
+	b := cover.ProfileBlock{StartLine: 2, StartCol: 16, EndLine: 2, EndCol: 30}
+	if got := blockSegment(line, 2, b); got != "{ return err }" {
+		t.Errorf("unexpected segment %q", got)
+	}
+	if hasCode("  { ") {
+		t.Error("braces and whitespace should not count as code")
+	}
+	if !hasCode("{ return err }") {
+		t.Error("return statement should count as code")
+	}
+}
+
+func TestComputeLineCounts(t *testing.T) {
+	lines := []string{"a", "b", "c", "d"}
+	blocks := []cover.ProfileBlock{
+		{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 3},
+		{StartLine: 2, EndLine: 3, NumStmt: 1, Count: 14203},
+		{StartLine: 4, EndLine: 4, NumStmt: 0, Count: 99},
+	}
+
+	counts := computeLineCounts(lines, blocks)
+
+	want := []int{3, 14203, 14203, 0}
+	for i, w := range want {
+		if counts[i] != w {
+			t.Errorf("line %d: expected count %d, got %d", i+1, w, counts[i])
+		}
+	}
+}
+
+func TestComputeLineCountsElseIf(t *testing.T) {
+	lines := []string{
+		"package ei",
+		"",
+		"func Sign(x int) int {",
+		"\tif x > 5 {",
+		"\t\treturn 1",
+		"\t} else if x < -5 {",
+		"\t\treturn -1",
+		"\t}",
+		"\treturn 0",
+		"}",
+	}
+
+	// Blocks as emitted by the cover tool for the function above, called
+	// twice with a positive argument.
+	blocks := []cover.ProfileBlock{
+		{StartLine: 4, StartCol: 2, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 2},
+		{StartLine: 5, StartCol: 3, EndLine: 6, EndCol: 1, NumStmt: 1, Count: 2},
+		{StartLine: 6, StartCol: 9, EndLine: 6, EndCol: 19, NumStmt: 1, Count: 0},
+		{StartLine: 7, StartCol: 3, EndLine: 8, EndCol: 1, NumStmt: 1, Count: 0},
+		{StartLine: 9, StartCol: 2, EndLine: 9, EndCol: 10, NumStmt: 1, Count: 0},
+	}
+
+	coverage := computeLineCoverage(lines, blocks)
+	counts := computeLineCounts(lines, blocks)
+
+	if coverage[5] != 1 {
+		t.Errorf("else if line should be uncovered (1), got %d", coverage[5])
+	}
+	wantCounts := []int{0, 0, 0, 2, 2, 0, 0, 0, 0, 0}
+	for i, w := range wantCounts {
+		if counts[i] != w {
+			t.Errorf("line %d: expected count %d, got %d", i+1, w, counts[i])
+		}
+		if covered := coverage[i] == 2 || coverage[i] == 3; covered != (counts[i] > 0) {
+			t.Errorf("line %d: count %d does not match coverage %d", i+1, counts[i], coverage[i])
+		}
+	}
+}
+
 func TestBuildTree(t *testing.T) {
 	files := []model.FileData{
 		{ID: 0, Path: "internal/parser/parser.go"},
@@ -200,7 +304,7 @@ go 1.21
 		t.Fatalf("failed to create go.mod: %v", err)
 	}
 
-	modPath, err := detectModulePath(tmpDir)
+	modPath, err := detectModulePath(os.DirFS(tmpDir), ".")
 	if err != nil {
 		t.Fatalf("detectModulePath failed: %v", err)
 	}
@@ -218,7 +322,7 @@ func TestDetectModulePathNoModule(t *testing.T) {
 		t.Fatalf("failed to create go.mod: %v", err)
 	}
 
-	_, err = detectModulePath(tmpDir)
+	_, err = detectModulePath(os.DirFS(tmpDir), ".")
 	if err == nil {
 		t.Error("expected error for missing module directive")
 	}
@@ -226,7 +330,7 @@ func TestDetectModulePathNoModule(t *testing.T) {
 
 func TestDetectModulePathNoFile(t *testing.T) {
 	tmpDir := t.TempDir()
-	_, err := detectModulePath(tmpDir)
+	_, err := detectModulePath(os.DirFS(tmpDir), ".")
 	if err == nil {
 		t.Error("expected error for missing go.mod")
 	}
@@ -241,7 +345,7 @@ func TestReadLines(t *testing.T) {
 		t.Fatalf("failed to create test file: %v", err)
 	}
 
-	lines, err := readLines(path)
+	lines, err := readLines(os.DirFS(tmpDir), filepath.Base(path))
 	if err != nil {
 		t.Fatalf("readLines failed: %v", err)
 	}
@@ -261,7 +365,7 @@ func TestReadLinesEmpty(t *testing.T) {
 		t.Fatalf("failed to create empty file: %v", err)
 	}
 
-	lines, err := readLines(path)
+	lines, err := readLines(os.DirFS(tmpDir), filepath.Base(path))
 	if err != nil {
 		t.Fatalf("readLines failed: %v", err)
 	}
@@ -271,7 +375,7 @@ func TestReadLinesEmpty(t *testing.T) {
 }
 
 func TestReadLinesNotFound(t *testing.T) {
-	_, err := readLines("/nonexistent/path/file.txt")
+	_, err := readLines(os.DirFS("/nonexistent"), "path/file.txt")
 	if err == nil {
 		t.Error("expected error for nonexistent file")
 	}
@@ -375,6 +479,94 @@ testmod/main.go:7.14,9.2 1 0
 	}
 }
 
+func TestParseCountMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module testmod\n"), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to create go.mod: %v", err)
+	}
+	srcContent := "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(srcContent), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to create main.go: %v", err)
+	}
+	coverageProfile := `mode: count
+testmod/main.go:3.13,5.2 1 42
+`
+	coveragePath := filepath.Join(tmpDir, "coverage.out")
+	if err := os.WriteFile(coveragePath, []byte(coverageProfile), 0o644); err != nil { //nolint:gosec // test file
+		t.Fatalf("failed to create coverage.out: %v", err)
+	}
+
+	data, err := Parse(coveragePath, tmpDir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if data.Mode != "count" {
+		t.Errorf("expected mode 'count', got '%s'", data.Mode)
+	}
+	file := data.Files[0]
+	if file.Counts[3] != 42 {
+		t.Errorf("line 4 should have count 42, got %d", file.Counts[3])
+	}
+	if file.Counts[0] != 0 {
+		t.Errorf("line 1 should have count 0, got %d", file.Counts[0])
+	}
+	if len(file.Blocks) != 1 || file.Blocks[0].Count != 42 || file.Blocks[0].NumStmt != 1 {
+		t.Errorf("unexpected blocks: %+v", file.Blocks)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod": "module testmod\n",
+		"main.go": `package main
+
+func main() {
+	a, b := 1, 2; println(a, b); println(a + b)
+	if a > b {
+		println("never")
+	}
+}
+`,
+		// 3 of 5 lines but 4 of 5 statements are covered
+		"coverage.out": `mode: set
+testmod/main.go:3.13,5.12 4 1
+testmod/main.go:5.12,7.3 1 0
+`,
+	})
+	coveragePath := filepath.Join(tmpDir, "coverage.out")
+
+	tests := []struct {
+		metric  string
+		percent float64
+	}{
+		{"", 60},
+		{model.MetricLines, 60},
+		{model.MetricStatements, 80},
+	}
+	for _, tt := range tests {
+		data, err := ParseFiles([]string{coveragePath}, tmpDir, Options{Metric: tt.metric})
+		if err != nil {
+			t.Fatalf("ParseFiles(%q) failed: %v", tt.metric, err)
+		}
+		s := data.Summary
+		if s.TotalStatements != 5 || s.CoveredStatements != 4 || s.TotalLines != 5 || s.CoveredLines != 3 {
+			t.Errorf("metric %q: unexpected totals %+v", tt.metric, s)
+		}
+		if s.LinePercent != 60 || s.StatementPercent != 80 {
+			t.Errorf("metric %q: unexpected percentages %+v", tt.metric, s)
+		}
+		if s.Percent != tt.percent {
+			t.Errorf("metric %q: expected %.1f%%, got %.1f%%", tt.metric, tt.percent, s.Percent)
+		}
+	}
+
+	if _, err := ParseFiles([]string{coveragePath}, tmpDir, Options{Metric: "branches"}); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
 func TestParseNoGoMod(t *testing.T) {
 	tmpDir := t.TempDir()
 	coveragePath := filepath.Join(tmpDir, "coverage.out")
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// ParseDiffHunks reads a unified diff, as written by "git diff -U0", and
+// returns the ranges of lines added or modified in each file, by new path.
+// Files with only deleted lines are present with no hunks.
+func ParseDiffHunks(r io.Reader) (map[string][]model.Hunk, error) {
+	hunks := make(map[string][]model.Hunk)
+	current := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			current = diffPath(strings.TrimPrefix(line, "+++ "))
+			if current != "" {
+				hunks[current] = nil
+			}
+		case strings.HasPrefix(line, "@@ ") && current != "":
+			hunk, ok, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", current, err)
+			}
+			if ok {
+				hunks[current] = append(hunks[current], hunk)
+			}
+		}
+	}
+	return hunks, scanner.Err()
+}
+
+// diffPath returns the path of a "+++" header without its "b/" prefix, or ""
+// for deleted files.
+func diffPath(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+	}
+	if name == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(name, "b/")
+}
+
+// parseHunkHeader parses the new file range of a "@@ -a,b +c,d @@" header.
+// It reports false for hunks that only delete lines.
+func parseHunkHeader(line string) (model.Hunk, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return model.Hunk{}, false, fmt.Errorf("invalid hunk header %q", line)
+	}
+	start, count, found := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	first, err := strconv.Atoi(start)
+	if err != nil {
+		return model.Hunk{}, false, fmt.Errorf("invalid hunk header %q: %w", line, err)
+	}
+	n := 1
+	if found {
+		if n, err = strconv.Atoi(count); err != nil {
+			return model.Hunk{}, false, fmt.Errorf("invalid hunk header %q: %w", line, err)
+		}
+	}
+	if n == 0 {
+		return model.Hunk{}, false, nil
+	}
+	return model.Hunk{Start: first, End: first + n - 1}, true, nil
+}
+
+// ApplyPatch keeps the files changed by a patch, with the hunks of each file,
+// and computes the patch coverage over their changed lines.
+func ApplyPatch(data *model.CoverageData, hunks map[string][]model.Hunk) *model.CoverageData {
+	if data == nil {
+		return nil
+	}
+
+	files := make([]model.FileData, 0, len(data.Files))
+	for _, file := range data.Files {
+		fileHunks, ok := hunks[file.Path]
+		if !ok {
+			continue
+		}
+		// Hunks past the end of the file, when the sources are not those of
+		// the diff, are cut so that the report can be read back
+		file.Hunks = nil
+		for _, h := range fileHunks {
+			if h.Start <= len(file.Coverage) {
+				h.End = min(h.End, len(file.Coverage))
+				file.Hunks = append(file.Hunks, h)
+			}
+		}
+		files = append(files, file)
+	}
+
+	result := *data
+	result.Patch = &model.PatchSummary{}
+	return withFiles(&result, files)
+}
+
+// summarizePatch computes the coverage of the lines with statements inside
+// the hunks of files.
+func summarizePatch(files []model.FileData) *model.PatchSummary {
+	var patch model.PatchSummary
+	for _, file := range files {
+		for _, h := range file.Hunks {
+			for i := h.Start - 1; i < h.End && i < len(file.Coverage); i++ {
+				switch file.Coverage[i] {
+				case 1:
+					patch.TotalLines++
+				case 2:
+					patch.TotalLines++
+					patch.CoveredLines++
+				case 3:
+					patch.TotalLines++
+					patch.PartialLines++
+				}
+			}
+		}
+	}
+	if patch.TotalLines > 0 {
+		patch.Percent = float64(patch.CoveredLines) / float64(patch.TotalLines) * 100
+	}
+	return &patch
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestParseDiffHunks(t *testing.T) {
+	diff := `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -3 +3 @@ func main() {
+-	old()
++	updated()
+@@ -10,0 +11,3 @@ func main() {
++	a()
++	b()
++	c()
+@@ -20,2 +23,0 @@ func main() {
+-	gone()
+-	gone()
+diff --git a/docs/only_deleted.go b/docs/only_deleted.go
+--- a/docs/only_deleted.go
++++ b/docs/only_deleted.go
+@@ -1,2 +0,0 @@
+-package docs
+-
+diff --git "a/odd name.go" "b/odd name.go"
+new file mode 100644
+--- /dev/null
++++ "b/odd name.go"
+@@ -0,0 +1,2 @@
++package odd
++
+`
+	hunks, err := ParseDiffHunks(strings.NewReader(diff))
+	if err != nil {
+		t.Fatalf("ParseDiffHunks failed: %v", err)
+	}
+
+	want := map[string][]model.Hunk{
+		"app.go":               {{Start: 3, End: 3}, {Start: 11, End: 13}},
+		"docs/only_deleted.go": nil,
+		"odd name.go":          {{Start: 1, End: 2}},
+	}
+	if len(hunks) != len(want) {
+		t.Fatalf("expected %d files, got %v", len(want), hunks)
+	}
+	for path, wantHunks := range want {
+		got, ok := hunks[path]
+		if !ok {
+			t.Errorf("missing %s", path)
+			continue
+		}
+		if len(got) != len(wantHunks) {
+			t.Errorf("%s: expected %v, got %v", path, wantHunks, got)
+			continue
+		}
+		for i := range wantHunks {
+			if got[i] != wantHunks[i] {
+				t.Errorf("%s hunk %d: expected %v, got %v", path, i, wantHunks[i], got[i])
+			}
+		}
+	}
+}
+
+func TestParseDiffHunksInvalid(t *testing.T) {
+	_, err := ParseDiffHunks(strings.NewReader("+++ b/app.go\n@@ -1 +x @@\n"))
+	if err == nil || !strings.Contains(err.Error(), "app.go: invalid hunk header") {
+		t.Errorf("expected an invalid hunk header error, got %v", err)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	data := withFiles(&model.CoverageData{Summary: model.Summary{Metric: model.MetricLines}}, []model.FileData{
+		{Path: "a.go", Coverage: []int{0, 2, 1, 3, 2, 1}},
+		{Path: "b.go", Coverage: []int{0, 1, 1}},
+	})
+
+	patched := ApplyPatch(data, map[string][]model.Hunk{
+		"a.go":     {{Start: 1, End: 3}, {Start: 5, End: 9}},
+		"other.go": {{Start: 1, End: 1}},
+	})
+	if len(patched.Files) != 1 || patched.Files[0].Path != "a.go" || len(patched.Files[0].Hunks) != 2 {
+		t.Fatalf("expected only a.go with its hunks, got %+v", patched.Files)
+	}
+	want := model.PatchSummary{TotalLines: 4, CoveredLines: 2, Percent: 50}
+	if patched.Patch == nil || *patched.Patch != want {
+		t.Errorf("expected patch summary %+v, got %+v", want, patched.Patch)
+	}
+	if patched.Summary.TotalLines != 5 {
+		t.Errorf("the summary should cover the whole changed files, got %+v", patched.Summary)
+	}
+
+	// Filtering again recomputes the patch summary
+	excluded := FilterByPaths(patched, map[string]struct{}{})
+	if excluded.Patch == nil || excluded.Patch.TotalLines != 0 {
+		t.Errorf("expected an empty patch summary, got %+v", excluded.Patch)
+	}
+}
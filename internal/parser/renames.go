@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+// minRenameSimilarity is the share of lines a base file and a current file
+// must have in common to be considered a rename, as git's default -M50%.
+const minRenameSimilarity = 0.5
+
+// DetectRenames pairs the base files missing from current with the current
+// files missing from base by content similarity, and returns a map of current
+// paths to base paths. Files with the same content are paired first, then the
+// others most similar first, each file at most once.
+func DetectRenames(base, current *model.CoverageData) map[string]string {
+	inBase := make(map[string]bool)
+	for _, f := range base.Files {
+		inBase[f.Path] = true
+	}
+	inCurrent := make(map[string]bool)
+	for _, f := range current.Files {
+		inCurrent[f.Path] = true
+	}
+
+	// Files moved without changes are paired by a hash of their content
+	renames := make(map[string]string)
+	byContent := make(map[[sha256.Size]byte][]string)
+	for _, b := range base.Files {
+		if inCurrent[b.Path] || len(b.Lines) == 0 {
+			continue
+		}
+		sum := contentHash(b.Lines)
+		byContent[sum] = append(byContent[sum], b.Path)
+	}
+	used := make(map[string]bool)
+	for _, c := range current.Files {
+		if inBase[c.Path] || len(c.Lines) == 0 {
+			continue
+		}
+		sum := contentHash(c.Lines)
+		if paths := byContent[sum]; len(paths) > 0 {
+			renames[c.Path] = paths[0]
+			used[paths[0]] = true
+			byContent[sum] = paths[1:]
+		}
+	}
+
+	var bases, currents []*renameFile
+	for _, b := range base.Files {
+		if !inCurrent[b.Path] && len(b.Lines) > 0 && !used[b.Path] {
+			bases = append(bases, newRenameFile(b))
+		}
+	}
+	for _, c := range current.Files {
+		if _, ok := renames[c.Path]; !ok && !inBase[c.Path] && len(c.Lines) > 0 {
+			currents = append(currents, newRenameFile(c))
+		}
+	}
+
+	type candidate struct {
+		basePath, currPath string
+		similarity         float64
+	}
+	var candidates []candidate
+	for _, b := range bases {
+		for _, c := range currents {
+			if similarity := b.similarity(c); similarity >= minRenameSimilarity {
+				candidates = append(candidates, candidate{b.path, c.path, similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	for _, c := range candidates {
+		if _, ok := renames[c.currPath]; ok || used[c.basePath] {
+			continue
+		}
+		renames[c.currPath] = c.basePath
+		used[c.basePath] = true
+	}
+	return renames
+}
+
+// contentHash returns the hash of the content of a file.
+func contentHash(lines []string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// renameFile holds the significant lines of a rename candidate, with the
+// number of times each occurs.
+type renameFile struct {
+	path   string
+	lines  []string
+	counts map[string]int
+}
+
+func newRenameFile(f model.FileData) *renameFile {
+	r := &renameFile{path: f.Path, lines: significantLines(f.Lines), counts: make(map[string]int)}
+	for _, line := range r.lines {
+		r.counts[line]++
+	}
+	return r
+}
+
+// similarity returns the share of the significant lines of r and o that are
+// aligned with each other, from 0 to 1. As in git, the lines the files have
+// in common regardless of their order are counted first, which bounds the
+// similarity, so that most pairs are rejected without aligning them.
+func (r *renameFile) similarity(o *renameFile) float64 {
+	total := len(r.lines) + len(o.lines)
+	// Files too different in size cannot reach the minimum similarity
+	if total == 0 || float64(2*min(len(r.lines), len(o.lines))) < minRenameSimilarity*float64(total) {
+		return 0
+	}
+
+	small, large := r.counts, o.counts
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	common := 0
+	for line, n := range small {
+		common += min(n, large[line])
+	}
+	if float64(2*common) < minRenameSimilarity*float64(total) {
+		return 0
+	}
+
+	matched := 0
+	for _, baseIdx := range alignLines(r.lines, o.lines) {
+		if baseIdx >= 0 {
+			matched++
+		}
+	}
+	return float64(2*matched) / float64(total)
+}
+
+// significantLines returns the lines longer than one character, trimmed.
+// Blank lines and lines of a single character such as closing braces are left
+// out, as every Go file has them.
+func significantLines(lines []string) []string {
+	var significant []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); len(line) > 1 {
+			significant = append(significant, line)
+		}
+	}
+	return significant
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func TestDetectRenames(t *testing.T) {
+	server := strings.Split("package app\n\nfunc Serve() {\n\tlisten()\n\thandle()\n}\n", "\n")
+	edited := strings.Split("package app\n\n// Serve serves.\nfunc Serve() {\n\tlisten()\n\thandle()\n}\n", "\n")
+	other := strings.Split("package app\n\nfunc Other() {\n\treturn\n}\n", "\n")
+	braces := strings.Split("package app\n\nfunc Unrelated() {\n\tx()\n}\n", "\n")
+
+	base := &model.CoverageData{Files: []model.FileData{
+		{Path: "server.go", Lines: server},
+		{Path: "kept.go", Lines: other},
+		{Path: "gone.go", Lines: braces},
+	}}
+	current := &model.CoverageData{Files: []model.FileData{
+		{Path: "http/server.go", Lines: edited},
+		{Path: "kept.go", Lines: other},
+		{Path: "new.go", Lines: other},
+	}}
+
+	renames := DetectRenames(base, current)
+	if len(renames) != 1 || renames["http/server.go"] != "server.go" {
+		t.Errorf("expected http/server.go to be renamed from server.go, got %v", renames)
+	}
+}
+
+// TestDetectRenamesMany moves hundreds of files, half of them edited, which
+// should be paired without aligning every base file with every current one.
+func TestDetectRenamesMany(t *testing.T) {
+	const files = 400
+	base := &model.CoverageData{}
+	current := &model.CoverageData{}
+	for i := range files {
+		lines := []string{"package app", ""}
+		for j := range 50 {
+			lines = append(lines, fmt.Sprintf("func F%d_%d() { work(%d) }", i, j, j))
+		}
+		base.Files = append(base.Files, model.FileData{Path: fmt.Sprintf("old/f%d.go", i), Lines: lines})
+		if i%2 == 1 {
+			lines = append(slices.Clone(lines), "// edited")
+		}
+		current.Files = append(current.Files, model.FileData{Path: fmt.Sprintf("new/f%d.go", i), Lines: lines})
+	}
+
+	renames := DetectRenames(base, current)
+	if len(renames) != files {
+		t.Fatalf("expected %d renames, got %d", files, len(renames))
+	}
+	for i := range files {
+		if got := renames[fmt.Sprintf("new/f%d.go", i)]; got != fmt.Sprintf("old/f%d.go", i) {
+			t.Fatalf("expected new/f%d.go to be renamed from old/f%d.go, got %q", i, i, got)
+		}
+	}
+}
+
+func TestComputeDiffDeletedAndRenamed(t *testing.T) {
+	base := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "old.go", Lines: []string{"a", "b"}, Coverage: []int{2, 2}},
+			{Path: "gone.go", Lines: []string{"x", "y", "z"}, Coverage: []int{2, 1, 0}},
+		},
+	}
+	current := &model.CoverageData{
+		Files: []model.FileData{
+			{Path: "new.go", Lines: []string{"a", "b"}, Coverage: []int{2, 1}},
+		},
+	}
+
+	result := ComputeDiffWithRenames(base, current, map[string]string{"new.go": "old.go"})
+
+	file := result.Files[0]
+	if file.BasePath != "old.go" || file.Base == nil {
+		t.Fatalf("expected new.go to be compared with old.go, got %+v", file)
+	}
+	if file.DiffState[1] != DiffStateNewlyUncovered {
+		t.Errorf("expected a regression on line 2, got %v", file.DiffState)
+	}
+	if len(result.DeletedFiles) != 1 || result.DeletedFiles[0].Path != "gone.go" ||
+		result.DeletedFiles[0].Totals.CoveredLines != 1 {
+		t.Errorf("expected gone.go to be deleted, got %+v", result.DeletedFiles)
+	}
+	summary := result.DiffSummary
+	if summary.DeletedFiles != 1 || summary.DeletedCoveredLines != 1 || summary.RenamedFiles != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	// Without renames, the moved file is new code and its base file deleted
+	result = ComputeDiffWithRenames(base, current, map[string]string{})
+	if result.Files[0].BasePath != "" || result.DiffSummary.DeletedFiles != 2 || result.DiffSummary.RenamedFiles != 0 {
+		t.Errorf("unexpected diff without renames: %+v", result.DiffSummary)
+	}
+}
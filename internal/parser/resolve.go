@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+	"golang.org/x/tools/cover"
+)
+
+// resolver locates the source file of each profile entry.
+type resolver struct {
+	srcRoot  string
+	src      fs.FS // source tree rooted at srcRoot
+	modules  []module
+	deps     bool                     // look in vendor/ and the go list results
+	packages map[string]goListPackage // import path -> package, from go list
+}
+
+// goListPackage is the part of the "go list -json" output used to find the
+// sources of a package.
+type goListPackage struct {
+	ImportPath string
+	Dir        string
+	Module     *struct {
+		Path    string
+		Version string
+		Dir     string
+	}
+}
+
+func newResolver(srcRoot string, src fs.FS, modules []module, profiles []*cover.Profile, resolveDeps bool) (*resolver, error) {
+	r := &resolver{srcRoot: srcRoot, src: src, modules: modules, deps: resolveDeps}
+	if !resolveDeps {
+		return r, nil
+	}
+
+	// Ask go list only about the packages that are neither part of the
+	// source tree nor vendored.
+	var importPaths []string
+	seen := make(map[string]struct{})
+	for _, p := range profiles {
+		if _, ok := modulePath(modules, p.FileName); ok {
+			continue
+		}
+		if r.vendored(p.FileName) {
+			continue
+		}
+		pkg := path.Dir(p.FileName)
+		if _, ok := seen[pkg]; ok {
+			continue
+		}
+		seen[pkg] = struct{}{}
+		importPaths = append(importPaths, pkg)
+	}
+	if len(importPaths) == 0 {
+		return r, nil
+	}
+
+	packages, err := goList(srcRoot, importPaths)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependencies with go list: %w", err)
+	}
+	r.packages = packages
+	return r, nil
+}
+
+// resolve returns the report path and the lines of the source file for a
+// profile file name, along with a diagnostic recording every location tried.
+func (r *resolver) resolve(fileName string) (string, []string, model.Diagnostic) {
+	diag := model.Diagnostic{FileName: fileName}
+
+	try := func(method, relPath string, fsys fs.FS, name string) ([]string, bool) {
+		lines, err := readLines(fsys, name)
+		attempt := model.ResolveAttempt{Method: method, Path: relPath}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		diag.Attempts = append(diag.Attempts, attempt)
+		if err != nil {
+			return nil, false
+		}
+		diag.Path, diag.Method = relPath, method
+		return lines, true
+	}
+
+	// Convert module path to file path, or use it as is when no module of
+	// the source tree owns it
+	method := "module"
+	relPath, owned := modulePath(r.modules, fileName)
+	if !owned {
+		method, relPath = "relative", fileName
+	}
+	if lines, ok := try(method, relPath, r.src, relPath); ok {
+		return relPath, lines, diag
+	}
+
+	// Try stripping module prefix differently
+	if parts := strings.SplitN(fileName, "/", 4); len(parts) >= 4 {
+		if lines, ok := try("strip-prefix", parts[3], r.src, parts[3]); ok {
+			return parts[3], lines, diag
+		}
+	}
+
+	if r.deps {
+		for _, m := range r.modules {
+			relPath := path.Join(m.Dir, "vendor", fileName)
+			if lines, ok := try("vendor", relPath, r.src, relPath); ok {
+				return relPath, lines, diag
+			}
+		}
+
+		pkg, ok := r.packages[path.Dir(fileName)]
+		if ok && pkg.Dir != "" {
+			fullPath := filepath.Join(pkg.Dir, path.Base(fileName))
+			relPath, inTree := r.listedPath(pkg, fullPath, fileName)
+			// Files of the source tree are read from the same sources as
+			// the rest of the tree, the others from disk.
+			fsys, name := r.src, relPath
+			if !inTree {
+				fsys, name = os.DirFS(pkg.Dir), path.Base(fileName)
+			}
+			if lines, ok := try("go-list", relPath, fsys, name); ok {
+				return relPath, lines, diag
+			}
+		} else {
+			diag.Attempts = append(diag.Attempts, model.ResolveAttempt{
+				Method: "go-list",
+				Path:   path.Dir(fileName),
+				Error:  "package not found by go list",
+			})
+		}
+	}
+
+	diag.Skipped = true
+	diag.Reason = "source file not found"
+	if !r.deps && !owned {
+		diag.Reason += " (not part of the source tree, try -resolve-deps)"
+	}
+	return "", nil, diag
+}
+
+// vendored reports whether a file is in the vendor directory of the source
+// root or of one of its modules.
+func (r *resolver) vendored(fileName string) bool {
+	for _, m := range r.modules {
+		relPath := path.Join(m.Dir, "vendor", fileName)
+		if _, err := fs.Stat(r.src, relPath); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// listedPath returns the report path of a file found through go list and
+// whether the file is part of the source tree. Files of the module cache are
+// reported as "module@version/path" so that they are grouped under their
+// module in the tree.
+func (r *resolver) listedPath(pkg goListPackage, fullPath, fileName string) (string, bool) {
+	if rel, err := filepath.Rel(r.srcRoot, fullPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel), true
+	}
+	if m := pkg.Module; m != nil && m.Dir != "" {
+		if rel, err := filepath.Rel(m.Dir, fullPath); err == nil {
+			root := m.Path
+			if m.Version != "" {
+				root += "@" + m.Version
+			}
+			return path.Join(root, filepath.ToSlash(rel)), false
+		}
+	}
+	return fileName, false
+}
+
+// goList runs "go list" in dir and returns the packages found, keyed by
+// import path.
+func goList(dir string, importPaths []string) (map[string]goListPackage, error) {
+	args := append([]string{"list", "-e", "-json=ImportPath,Dir,Module"}, importPaths...)
+	cmd := exec.Command("go", args...) //nolint:gosec // G204: import paths come from the coverage profile
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	packages := make(map[string]goListPackage)
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("decoding go list output: %w", err)
+		}
+		packages[pkg.ImportPath] = pkg
+	}
+	return packages, nil
+}
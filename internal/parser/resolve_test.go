@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/chmouel/go-better-html-coverage/internal/model"
+)
+
+func fileByPath(data *model.CoverageData, path string) *model.FileData {
+	for i := range data.Files {
+		if data.Files[i].Path == path {
+			return &data.Files[i]
+		}
+	}
+	return nil
+}
+
+func TestParseResolveDepsVendor(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":                            "module example.com/app\n",
+		"main.go":                           "package main\n\nfunc main() {\n\tprintln(\"main\")\n}\n",
+		"vendor/github.com/acme/lib/lib.go": "package lib\n\nfunc Do() {\n\tprintln(\"do\")\n}\n",
+		"coverage.out": `mode: set
+example.com/app/main.go:3.13,5.2 1 1
+github.com/acme/lib/lib.go:3.11,5.2 1 0
+`,
+	})
+	profile := filepath.Join(tmpDir, "coverage.out")
+
+	data, err := ParseFiles([]string{profile}, tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	if len(data.Files) != 1 {
+		t.Fatalf("expected vendored file to be skipped by default, got %d files", len(data.Files))
+	}
+
+	data, err = ParseFiles([]string{profile}, tmpDir, Options{ResolveDeps: true})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	if len(data.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(data.Files))
+	}
+	vendored := fileByPath(data, "vendor/github.com/acme/lib/lib.go")
+	if vendored == nil {
+		t.Fatalf("expected vendored path, got %+v", data.Files)
+	}
+	if vendored.Coverage[3] != 1 {
+		t.Errorf("expected vendored line 4 to be uncovered, got %d", vendored.Coverage[3])
+	}
+}
+
+func TestParseResolveDepsGoList(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOWORK", "off")
+
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "app")
+	writeFiles(t, tmpDir, map[string]string{
+		"app/go.mod": `module example.com/app
+
+go 1.21
+
+require example.com/dep v0.1.0
+
+replace example.com/dep => ../dep
+`,
+		"app/main.go":   "package main\n\nfunc main() {\n\tprintln(\"main\")\n}\n",
+		"dep/go.mod":    "module example.com/dep\n\ngo 1.21\n",
+		"dep/util/u.go": "package util\n\nfunc Help() {\n\tprintln(\"help\")\n}\n",
+		"coverage.out": `mode: set
+example.com/app/main.go:3.13,5.2 1 1
+example.com/dep/util/u.go:3.13,5.2 1 1
+`,
+	})
+
+	data, err := ParseFiles([]string{filepath.Join(tmpDir, "coverage.out")}, appDir, Options{ResolveDeps: true})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	if len(data.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(data.Files))
+	}
+	if fileByPath(data, "example.com/dep@v0.1.0/util/u.go") == nil {
+		t.Errorf("expected dependency grouped under its module, got %s", data.Files[1].Path)
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod": "module example.com/app\n",
+		"b.go":   "package main\n\nfunc b() {\n\tprintln(\"b\")\n}\n",
+		"coverage.out": `mode: set
+example.com/app/a.go:3.10,5.2 1 1
+example.com/app/b.go:3.10,5.2 1 1
+`,
+	})
+
+	data, err := Parse(filepath.Join(tmpDir, "coverage.out"), tmpDir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(data.Files) != 1 || data.Files[0].ID != 0 {
+		t.Fatalf("expected b.go with ID 0, got %+v", data.Files)
+	}
+	if len(data.Diagnostics) != 2 {
+		t.Fatalf("expected a diagnostic per profile entry, got %d", len(data.Diagnostics))
+	}
+
+	missing := data.Diagnostics[0]
+	if !missing.Skipped || missing.FileName != "example.com/app/a.go" || missing.Reason == "" {
+		t.Errorf("unexpected diagnostic for missing file: %+v", missing)
+	}
+	if len(missing.Attempts) == 0 || missing.Attempts[0].Method != "module" || missing.Attempts[0].Path != "a.go" {
+		t.Errorf("expected module lookup to be recorded, got %+v", missing.Attempts)
+	}
+
+	found := data.Diagnostics[1]
+	if found.Skipped || found.Path != "b.go" || found.Method != "module" {
+		t.Errorf("unexpected diagnostic for resolved file: %+v", found)
+	}
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GitSources returns the source tree at srcRoot as it is in the git revision
+// rev, so that the report matches the commit that produced the profile rather
+// than the working tree.
+func GitSources(srcRoot, rev string) (fs.FS, error) {
+	output, err := git(srcRoot, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	topLevel, prefix, _ := strings.Cut(strings.TrimRight(string(output), "\n"), "\n")
+
+	// Archive the tree of srcRoot at rev, so its paths are relative to srcRoot
+	treeish := rev + ":" + strings.TrimSuffix(prefix, "/")
+	archive, err := git(topLevel, "archive", "--format=tar", treeish)
+	if err != nil {
+		return nil, fmt.Errorf("reading revision %s: %w", rev, err)
+	}
+	return readTar(bytes.NewReader(archive))
+}
+
+// ArchiveSources returns the source tree stored in a .zip, .tar, .tar.gz or
+// .tgz archive. When the archive holds a single top-level directory, as
+// release tarballs usually do, the tree is rooted in that directory.
+func ArchiveSources(archivePath string) (fs.FS, error) {
+	data, err := os.ReadFile(archivePath) //nolint:gosec // path is from the command line
+	if err != nil {
+		return nil, err
+	}
+
+	var fsys fs.FS
+	switch name := strings.ToLower(archivePath); {
+	case strings.HasSuffix(name, ".zip"):
+		fsys, err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(data))
+		if err == nil {
+			fsys, err = readTar(gz)
+		}
+	case strings.HasSuffix(name, ".tar"):
+		fsys, err = readTar(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported archive %s: expected .zip, .tar, .tar.gz or .tgz", archivePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", archivePath, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", archivePath, err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return fsys, nil
+}
+
+// git runs a git command in dir and returns its output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...) //nolint:gosec // G204: revision is from the command line
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// readTar loads the regular files of a tar stream into memory.
+func readTar(r io.Reader) (fs.FS, error) {
+	fsys := &memFS{files: make(map[string][]byte), dirs: map[string]map[string]struct{}{".": {}}}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fsys, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		fsys.add(name, data)
+	}
+}
+
+// memFS is a read-only in-memory file system.
+type memFS struct {
+	files map[string][]byte
+	dirs  map[string]map[string]struct{} // directory -> names of its entries
+}
+
+func (m *memFS) add(name string, data []byte) {
+	m.files[name] = data
+	for dir, child := path.Dir(name), path.Base(name); ; dir, child = path.Dir(dir), path.Base(dir) {
+		entries, ok := m.dirs[dir]
+		if !ok {
+			entries = make(map[string]struct{})
+			m.dirs[dir] = entries
+		}
+		entries[child] = struct{}{}
+		if ok || dir == "." {
+			return
+		}
+	}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := m.files[name]; ok {
+		return &memFile{info: memInfo{name: path.Base(name), size: int64(len(data))}, r: bytes.NewReader(data)}, nil
+	}
+	children, ok := m.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	names := make([]string, 0, len(children))
+	for child := range children {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		full := path.Join(name, child)
+		info := memInfo{name: child, size: int64(len(m.files[full]))}
+		if _, isDir := m.dirs[full]; isDir {
+			info = memInfo{name: child, dir: true}
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return &memDir{info: memInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() any           { return nil }
+
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+type memFile struct {
+	info memInfo
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Read(b []byte) (int, error) { return f.r.Read(b) }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
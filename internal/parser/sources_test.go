@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func writeTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write tar content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadTar(t *testing.T) {
+	data := writeTar(t, map[string]string{
+		"go.mod":            "module example.com/app\n",
+		"./cmd/app/main.go": "package main\n",
+		"internal/x/x.go":   "package x\n",
+	})
+
+	fsys, err := readTar(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readTar failed: %v", err)
+	}
+	if err := fstest.TestFS(fsys, "go.mod", "cmd/app/main.go", "internal/x/x.go"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"app-1.0/go.mod":  "module example.com/app\n",
+		"app-1.0/main.go": "package main\n",
+	}
+
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	if _, err := gz.Write(writeTar(t, files)); err != nil {
+		t.Fatalf("failed to compress tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+
+	archives := map[string][]byte{
+		"src.tar.gz": gzBuf.Bytes(),
+		"src.zip":    zipBuf.Bytes(),
+	}
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(archivePath, data, 0o644); err != nil { //nolint:gosec // test file
+				t.Fatalf("failed to write archive: %v", err)
+			}
+
+			fsys, err := ArchiveSources(archivePath)
+			if err != nil {
+				t.Fatalf("ArchiveSources failed: %v", err)
+			}
+			// The single top-level directory is the root of the tree
+			content, err := fs.ReadFile(fsys, "main.go")
+			if err != nil {
+				t.Fatalf("failed to read main.go: %v", err)
+			}
+			if string(content) != "package main\n" {
+				t.Errorf("unexpected content %q", content)
+			}
+		})
+	}
+
+	if _, err := ArchiveSources(filepath.Join(tmpDir, "src.rar")); err == nil {
+		t.Error("expected error for unsupported archive")
+	}
+}
+
+func TestParseFromGitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":  "module example.com/app\n",
+		"main.go": "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"coverage.out": `mode: set
+example.com/app/main.go:3.13,5.2 1 1
+`,
+	})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "go.mod", "main.go"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+
+	// Local edits must not show up in the report
+	writeFiles(t, tmpDir, map[string]string{
+		"main.go": "package main\n\n// local edit\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+	})
+
+	sources, err := GitSources(tmpDir, "HEAD")
+	if err != nil {
+		t.Fatalf("GitSources failed: %v", err)
+	}
+	data, err := ParseFiles([]string{filepath.Join(tmpDir, "coverage.out")}, tmpDir, Options{Sources: sources})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	if len(data.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(data.Files))
+	}
+	if got := data.Files[0].Lines[2]; got != "func main() {" {
+		t.Errorf("expected line 3 from HEAD, got %q", got)
+	}
+	if data.Files[0].Coverage[3] != 2 {
+		t.Errorf("expected line 4 to be covered, got %d", data.Files[0].Coverage[3])
+	}
+
+	if _, err := GitSources(tmpDir, "does-not-exist"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// findUntested returns the non-test Go files of the source tree that belong
+// to one of its modules, match the build constraints of the current platform
+// and are not in known, sorted by path.
+func findUntested(fsys fs.FS, modules []module, known map[string]struct{}) ([]string, error) {
+	ctxt := build.Default
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && skipDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		if _, ok := known[p]; ok || !inModule(modules, p) {
+			return nil
+		}
+		// Files whose build constraints cannot be read are not built either
+		if match, err := ctxt.MatchFile(path.Dir(p), name); err != nil || !match {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// inModule reports whether the slash-separated path p is inside the
+// directory of one of the modules.
+func inModule(modules []module, p string) bool {
+	for _, m := range modules {
+		if m.Dir == "." || strings.HasPrefix(p, m.Dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// untestedBlocks returns uncovered blocks for the statements of a Go file
+// that is not in the coverage profile, one block per statement with the
+// bodies of compound statements and function literals split out. This only
+// approximates the blocks of the cover tool: the lines are much the same, but
+// the cover tool counts the statements of its blocks differently, such as
+// once for each code range of a block split by blank lines or comments. Files
+// that fail to parse return no blocks.
+func untestedBlocks(filename string, lines []string) []cover.ProfileBlock {
+	fset := token.NewFileSet()
+	src := strings.Join(lines, "\n")
+	f, err := goparser.ParseFile(fset, filename, src, goparser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var blocks []cover.ProfileBlock
+	add := func(start, end token.Pos) {
+		s, e := fset.Position(start), fset.Position(end)
+		blocks = append(blocks, cover.ProfileBlock{
+			StartLine: s.Line,
+			StartCol:  s.Column,
+			EndLine:   e.Line,
+			EndCol:    e.Column,
+			NumStmt:   1,
+		})
+	}
+
+	var stmts func(list []ast.Stmt)
+	var stmt func(s ast.Stmt)
+
+	// simple adds a statement that has no body of its own, stopping its
+	// block at the first function literal, whose body is added separately.
+	simple := func(s ast.Stmt) {
+		end := s.End()
+		ast.Inspect(s, func(n ast.Node) bool {
+			lit, ok := n.(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+			if lit.Body.Lbrace+1 < end {
+				end = lit.Body.Lbrace + 1
+			}
+			stmts(lit.Body.List)
+			return false
+		})
+		add(s.Pos(), end)
+	}
+
+	stmt = func(s ast.Stmt) {
+		switch s := s.(type) {
+		case *ast.BlockStmt:
+			stmts(s.List)
+		case *ast.LabeledStmt:
+			stmt(s.Stmt)
+		case *ast.IfStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			stmts(s.Body.List)
+			if s.Else != nil {
+				stmt(s.Else)
+			}
+		case *ast.ForStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			stmts(s.Body.List)
+		case *ast.RangeStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			stmts(s.Body.List)
+		case *ast.SwitchStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			for _, c := range s.Body.List {
+				stmts(c.(*ast.CaseClause).Body)
+			}
+		case *ast.TypeSwitchStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			for _, c := range s.Body.List {
+				stmts(c.(*ast.CaseClause).Body)
+			}
+		case *ast.SelectStmt:
+			add(s.Pos(), s.Body.Lbrace+1)
+			for _, c := range s.Body.List {
+				stmts(c.(*ast.CommClause).Body)
+			}
+		case *ast.EmptyStmt:
+		default:
+			simple(s)
+		}
+	}
+	stmts = func(list []ast.Stmt) {
+		for _, s := range list {
+			stmt(s)
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Body != nil {
+				stmts(decl.Body.List)
+			}
+		case *ast.GenDecl:
+			// Function literals in package-level initializers
+			ast.Inspect(decl, func(n ast.Node) bool {
+				if lit, ok := n.(*ast.FuncLit); ok {
+					stmts(lit.Body.List)
+					return false
+				}
+				return true
+			})
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].StartLine != blocks[j].StartLine {
+			return blocks[i].StartLine < blocks[j].StartLine
+		}
+		return blocks[i].StartCol < blocks[j].StartCol
+	})
+	return blocks
+}
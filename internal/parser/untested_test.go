@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIncludeUntested(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":                  "module example.com/app\n",
+		"main.go":                 "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"main_test.go":            "package main\n",
+		"lib/lib.go":              "package lib\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"lib/lib_other.go":        "//go:build neverbuilt\n\npackage lib\n\nfunc Other() {}\n",
+		"lib/testdata/fixture.go": "package fixture\n",
+		"vendor/x/x.go":           "package x\n",
+		"coverage.out": `mode: set
+example.com/app/main.go:3.13,5.2 1 1
+`,
+	})
+	coveragePath := filepath.Join(tmpDir, "coverage.out")
+
+	data, err := Parse(coveragePath, tmpDir)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(data.Files) != 1 {
+		t.Fatalf("expected untested files to be left out by default, got %d files", len(data.Files))
+	}
+
+	data, err = ParseFiles([]string{coveragePath}, tmpDir, Options{IncludeUntested: true})
+	if err != nil {
+		t.Fatalf("ParseFiles failed: %v", err)
+	}
+	if len(data.Files) != 2 {
+		t.Fatalf("expected main.go and lib/lib.go, got %+v", data.Files)
+	}
+
+	lib := data.Files[1]
+	if lib.Path != "lib/lib.go" || !lib.NotInProfile || lib.ID != 1 {
+		t.Fatalf("unexpected untested file: %+v", lib)
+	}
+	if data.Files[0].NotInProfile {
+		t.Error("main.go is in the profile")
+	}
+	if lib.Coverage[3] != 1 {
+		t.Errorf("expected the return statement to be uncovered, got %d", lib.Coverage[3])
+	}
+	if len(lib.Functions) != 1 || lib.Functions[0].Statements != 1 || lib.Functions[0].Covered != 0 {
+		t.Errorf("unexpected functions: %+v", lib.Functions)
+	}
+	if data.Summary.TotalStatements != 2 || data.Summary.CoveredStatements != 1 {
+		t.Errorf("expected untested statements in the summary, got %+v", data.Summary)
+	}
+}
+
+func TestUntestedBlocks(t *testing.T) {
+	src := `package p
+
+var handler = func() {
+	println("init")
+}
+
+func f(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		if x > 0 {
+			total += x
+		} else {
+			continue
+		}
+	}
+	defer func() {
+		println(total)
+	}()
+	switch total {
+	case 0:
+		return 0
+	}
+	return total
+}
+`
+	blocks := untestedBlocks("p.go", strings.Split(src, "\n"))
+
+	// One block per statement, in source order
+	wantStarts := []int{4, 8, 9, 10, 11, 13, 16, 17, 19, 21, 23}
+	if len(blocks) != len(wantStarts) {
+		t.Fatalf("expected %d blocks, got %d: %+v", len(wantStarts), len(blocks), blocks)
+	}
+	for i, b := range blocks {
+		if b.StartLine != wantStarts[i] || b.NumStmt != 1 || b.Count != 0 {
+			t.Errorf("block %d: expected an uncovered statement on line %d, got %+v", i, wantStarts[i], b)
+		}
+	}
+
+	// Compound statements stop at their opening brace
+	if b := blocks[2]; b.EndLine != 9 {
+		t.Errorf("expected the range header to end on line 9, got %+v", b)
+	}
+	// The defer ends at the function literal, whose body is a block of its own
+	if b := blocks[6]; b.EndLine != 16 {
+		t.Errorf("expected the defer to end on line 16, got %+v", b)
+	}
+
+	if blocks := untestedBlocks("bad.go", []string{"package"}); blocks != nil {
+		t.Errorf("expected no blocks for a file that does not parse, got %+v", blocks)
+	}
+}
+
+func TestFindUntestedNestedModules(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"go.work":        "go 1.22\n\nuse ./svc\n",
+		"svc/go.mod":     "module example.com/svc\n",
+		"svc/svc.go":     "package svc\n",
+		"svc/known.go":   "package svc\n",
+		"scratch/tmp.go": "package scratch\n",
+	})
+
+	modules, err := findModules(os.DirFS(tmpDir))
+	if err != nil {
+		t.Fatalf("findModules failed: %v", err)
+	}
+	files, err := findUntested(os.DirFS(tmpDir), modules, map[string]struct{}{"svc/known.go": {}})
+	if err != nil {
+		t.Fatalf("findUntested failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "svc/svc.go" {
+		t.Errorf("expected only svc/svc.go, got %v", files)
+	}
+}
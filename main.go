@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,13 +12,20 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/chmouel/go-better-html-coverage/internal/badge"
+	"github.com/chmouel/go-better-html-coverage/internal/export"
+	"github.com/chmouel/go-better-html-coverage/internal/gate"
 	"github.com/chmouel/go-better-html-coverage/internal/generator"
 	"github.com/chmouel/go-better-html-coverage/internal/model"
 	"github.com/chmouel/go-better-html-coverage/internal/parser"
 )
 
+// exitGateFailed is the exit status when a coverage gate fails, to tell it
+// apart from errors.
+const exitGateFailed = 3
+
 type arrayFlags []string
 
 func (a *arrayFlags) String() string {
@@ -30,61 +39,197 @@ func (a *arrayFlags) Set(value string) error {
 
 func main() {
 	var (
-		profilePath     string
+		profilePaths    arrayFlags
 		basePath        string
 		outputPath      string
 		badgePath       string
 		badgeThresholds string
 		srcRoot         string
+		srcRef          string
+		srcArchive      string
+		metric          string
+		includeUntested bool
 		ref             string
 		noSyntax        bool
 		noOpen          bool
 		quiet           bool
+		funcMode        bool
+		resolveDeps     bool
+		verbose         bool
+		strict          bool
 		excludePatterns arrayFlags
+		exportSpecs     arrayFlags
+		format          string
+		fromJSON        string
+		maxRows         int
+		reportURL       string
+		maxAnnotations  int
+		sarifThresholds string
+		minCoverage     float64
+		maxRegressions  int
+		minDelta        float64
+		thresholdsPath  string
+		minPatch        float64
+		baseSrcRef      string
 	)
 
-	flag.StringVar(&profilePath, "profile", "coverage.out", "coverage profile path")
+	flag.Var(&profilePaths, "profile", "coverage profile, GOCOVERDIR directory or glob, repeat to merge several profiles (default \"coverage.out\")")
 	flag.StringVar(&basePath, "base", "", "base coverage profile for diff comparison")
-	flag.StringVar(&outputPath, "o", "-", "output HTML file")
+	flag.StringVar(&baseSrcRef, "base-src-ref", "", "read the sources of the -base profile from this git revision of the source root")
+	flag.StringVar(&outputPath, "o", "-", "output file, in the format given by -format")
+	flag.StringVar(&format, "format", "html", "format of the -o output: html or an export format ("+strings.Join(export.Formats(), ", ")+")")
+	flag.StringVar(&fromJSON, "from-json", "", "render from a report written with -format json instead of parsing coverage profiles")
 	flag.StringVar(&badgePath, "badge", "", "output SVG badge file")
 	flag.StringVar(&badgeThresholds, "badge-threshold", "40,70", "badge color thresholds (red,yellow) e.g., 40,70")
 	flag.StringVar(&srcRoot, "src", ".", "source root directory")
+	flag.StringVar(&srcRef, "src-ref", "", "read sources from this git revision of the source root instead of the working tree")
+	flag.StringVar(&srcArchive, "src-archive", "", "read sources from a .zip, .tar, .tar.gz or .tgz archive of the source tree")
+	flag.StringVar(&metric, "metric", model.MetricLines, "coverage metric for the summary, badge and tree: lines or statements (as reported by go test -cover)")
 	flag.StringVar(&ref, "ref", "", "git ref or range to filter coverage")
 	flag.BoolVar(&noSyntax, "no-syntax", false, "disable syntax highlighting by default")
 	flag.BoolVar(&noOpen, "n", false, "do not open browser")
 	flag.BoolVar(&quiet, "q", false, "quiet mode: suppress non-error output")
+	flag.BoolVar(&resolveDeps, "resolve-deps", false, "locate sources of dependencies and vendored packages (e.g. with -coverpkg=all) using vendor/ and go list")
+	flag.BoolVar(&includeUntested, "include-untested", false, "add the Go files of the source root missing from the profile, such as packages without tests, at 0% coverage")
+	flag.BoolVar(&verbose, "v", false, "verbose mode: show how each profile entry was resolved to a source file")
+	flag.BoolVar(&strict, "strict", false, "fail if the source of any profile entry cannot be found")
+	flag.BoolVar(&funcMode, "func", false, "print per-function coverage to stdout instead of generating the HTML report")
+	flag.Var(&exportSpecs, "export", "also export coverage as format=path, e.g. lcov=coverage.info, path - for stdout (can be repeated, formats: "+strings.Join(export.Formats(), ", ")+")")
+	flag.IntVar(&maxRows, "markdown-max-rows", 10, "maximum rows of the tables and lists of the markdown format")
+	flag.IntVar(&maxAnnotations, "max-annotations", 10, "maximum annotations of the github format, the rest is summed up in a notice")
+	flag.StringVar(&sarifThresholds, "sarif-threshold", "40,70", "file coverage thresholds (error,warning) for the level of the sarif results, e.g., 40,70")
+	flag.StringVar(&reportURL, "report-url", "", "URL of the published HTML report, to link files and lines to it from the markdown format")
+	flag.Float64Var(&minCoverage, "min-coverage", 0, "fail with exit status 3 when the total coverage percentage is below this value")
+	flag.IntVar(&maxRegressions, "max-regressions", 0, "fail with exit status 3 when more lines lost coverage than this value (requires -base)")
+	flag.Float64Var(&minDelta, "min-delta", 0, "fail with exit status 3 when the coverage changed by less than this percentage, e.g. -0.5 (requires -base)")
+	flag.Float64Var(&minPatch, "min-patch-coverage", 0, "fail with exit status 3 when the coverage of the lines changed by -ref is below this value")
+	flag.StringVar(&thresholdsPath, "thresholds", "", "file of \"pattern minimum\" rules, fail with exit status 3 when a matching directory is below its minimum coverage")
 	flag.Var(&excludePatterns, "exclude", "regex pattern to exclude files (can be repeated)")
 	flag.Parse()
 
 	// if outputPath is "-", it means stdout then don't try to open browser
-	if outputPath == "-" {
+	if outputPath == "-" || format != "html" {
 		noOpen = true
 	}
+	if format != "html" && !export.Supported(format) {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q, expected html or one of: %s\n", format, strings.Join(export.Formats(), ", "))
+		os.Exit(1)
+	}
 
-	// Parse coverage data
-	data, err := parser.Parse(profilePath, srcRoot)
+	exports, err := parseExports(exportSpecs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing -export: %v\n", err)
+		os.Exit(1)
+	}
+	var gates gate.Config
+	if thresholdsPath != "" {
+		gates.Rules, err = gate.ReadRules(thresholdsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading thresholds: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	levels, err := parseThresholds(sarifThresholds)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing coverage: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error parsing SARIF thresholds: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Compute diff if base profile is provided
-	if basePath != "" {
-		baseData, err := parser.Parse(basePath, srcRoot)
+	var data *model.CoverageData
+	if fromJSON != "" {
+		if len(profilePaths) > 0 || basePath != "" {
+			fmt.Fprintf(os.Stderr, "Error: -from-json cannot be used with -profile or -base\n")
+			os.Exit(1)
+		}
+		// Keep the metric of the report unless -metric is given
+		jsonMetric := ""
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "metric" {
+				jsonMetric = metric
+			}
+		})
+		data, err = parser.ReadJSON(fromJSON, jsonMetric)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading coverage JSON: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		if len(profilePaths) == 0 {
+			profilePaths = arrayFlags{"coverage.out"}
+		}
+		profiles, err := expandProfiles(profilePaths)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding coverage profiles: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Parse coverage data
+		parseOpts := parser.Options{ResolveDeps: resolveDeps, Metric: metric, IncludeUntested: includeUntested}
+		switch {
+		case srcRef != "" && srcArchive != "":
+			fmt.Fprintf(os.Stderr, "Error: -src-ref and -src-archive cannot be used together\n")
+			os.Exit(1)
+		case srcRef != "":
+			parseOpts.Sources, err = parser.GitSources(srcRoot, srcRef)
+		case srcArchive != "":
+			parseOpts.Sources, err = parser.ArchiveSources(srcArchive)
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing base coverage: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error reading sources: %v\n", err)
+			os.Exit(1)
+		}
+		data, err = parser.ParseFiles(profiles, srcRoot, parseOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing coverage: %v\n", err)
+			os.Exit(1)
+		}
+
+		skipped := skippedEntries(data.Diagnostics)
+		switch {
+		case verbose:
+			printDiagnostics(os.Stderr, data.Diagnostics)
+		case strict && len(skipped) > 0:
+			printDiagnostics(os.Stderr, skipped)
+		case len(skipped) > 0 && !quiet:
+			fmt.Fprintf(os.Stderr, "Warning: skipped %d of %d profile entries whose source was not found, use -v for details\n",
+				len(skipped), len(data.Diagnostics))
+		}
+		if strict && len(skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d profile entries could not be resolved to a source file\n", len(skipped))
 			os.Exit(1)
 		}
-		data = parser.ComputeDiff(baseData, data)
+
+		// Compute diff if base profile is provided
+		if basePath != "" {
+			baseData, err := readBase(basePath, srcRoot, baseSrcRef, parseOpts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing base coverage: %v\n", err)
+				os.Exit(1)
+			}
+			if baseSrcRef == "" && !parser.IsJSONReport(basePath) && !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: the base profile is read with the current sources, so lines edited since the base cannot be told apart, use -base-src-ref or a -format json base\n")
+			}
+			if n := len(baseData.DeletedFiles); n > 0 && !baseData.IsDiffMode && baseSrcRef == "" && !quiet {
+				fmt.Fprintf(os.Stderr, "Warning: %d base profile entries have no source in the current tree and are counted as deleted files from their blocks, use -base-src-ref to read the base sources from git\n", n)
+			}
+			// Renames come from git when the base revision is known
+			var renames map[string]string
+			if baseSrcRef != "" {
+				if renames, err = gitRenames(srcRoot, baseSrcRef, srcRef); err != nil && verbose {
+					fmt.Fprintf(os.Stderr, "Detecting renames by content, git rename detection failed: %v\n", err)
+				}
+			}
+			data = parser.ComputeDiffWithRenames(baseData, data, renames)
+		}
 	}
 
 	if ref != "" {
-		changedFiles, err := gitChangedFiles(srcRoot, ref)
+		changedLines, err := gitChangedLines(srcRoot, ref)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error resolving git changes: %v\n", err)
 			os.Exit(1)
 		}
-		data = parser.FilterByPaths(data, changedFiles)
+		data = parser.ApplyPatch(data, changedLines)
 	}
 
 	if len(excludePatterns) > 0 {
@@ -99,9 +244,59 @@ func main() {
 		}
 	}
 
-	// Generate HTML report
-	opts := generator.Options{NoSyntax: noSyntax}
-	if err := generator.Generate(data, outputPath, opts); err != nil {
+	// Gates are only checked when their flag is given, the gates of a JSON
+	// report are replaced
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "min-coverage":
+			gates.MinCoverage = &minCoverage
+		case "max-regressions":
+			gates.MaxRegressions = &maxRegressions
+		case "min-delta":
+			gates.MinDelta = &minDelta
+		case "min-patch-coverage":
+			gates.MinPatchCoverage = &minPatch
+		}
+	})
+	data.Gates, err = gate.Check(data, gates)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking coverage gates: %v\n", err)
+		os.Exit(1)
+	}
+
+	exportOpts := export.Options{SourceRoot: srcRoot, MaxRows: maxRows, ReportURL: reportURL, MaxAnnotations: maxAnnotations, Thresholds: levels}
+	if absRoot, err := filepath.Abs(srcRoot); err == nil {
+		exportOpts.SourceRoot = absRoot
+	}
+	for _, e := range exports {
+		if err := export.Write(data, e.format, e.path, exportOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting coverage: %v\n", err)
+			os.Exit(1)
+		}
+		if !quiet && e.path != "-" {
+			fmt.Fprintf(os.Stderr, "Coverage %s export written to %s\n", e.format, e.path)
+		}
+	}
+
+	if funcMode {
+		if err := writeFuncTable(os.Stdout, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing function coverage: %v\n", err)
+			os.Exit(1)
+		}
+		if !reportGates(os.Stderr, data.Gates, quiet) {
+			os.Exit(exitGateFailed)
+		}
+		return
+	}
+
+	// Generate the report, HTML unless another format is requested
+	if format == "html" {
+		opts := generator.Options{NoSyntax: noSyntax}
+		err = generator.Generate(data, outputPath, opts)
+	} else {
+		err = export.Write(data, format, outputPath, exportOpts)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating report: %v\n", err)
 		os.Exit(1)
 	}
@@ -112,14 +307,36 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Coverage: %.1f%% (Δ%+.1f%% from base)\n",
 				data.Summary.Percent,
 				data.DiffSummary.DeltaPercent)
-			fmt.Fprintf(os.Stderr, "Changes: +%d newly covered, -%d regressions\n",
+			fmt.Fprintf(os.Stderr, "Changes: +%d newly covered, -%d regressions, %d now partially covered\n",
 				data.DiffSummary.NewlyCoveredLines,
-				data.DiffSummary.NewlyUncoveredLines)
+				data.DiffSummary.NewlyUncoveredLines,
+				data.DiffSummary.NewlyPartialLines)
+			fmt.Fprintf(os.Stderr, "New code: %d covered, %d uncovered lines\n",
+				data.DiffSummary.NewCodeCoveredLines,
+				data.DiffSummary.NewCodeUncoveredLines)
+			if data.DiffSummary.DeletedFiles > 0 || data.DiffSummary.RenamedFiles > 0 {
+				fmt.Fprintf(os.Stderr, "Files: %d deleted (-%d covered lines), %d renamed\n",
+					data.DiffSummary.DeletedFiles,
+					data.DiffSummary.DeletedCoveredLines,
+					data.DiffSummary.RenamedFiles)
+			}
 		} else {
-			fmt.Fprintf(os.Stderr, "Coverage: %.1f%% (%d/%d lines)\n",
+			fmt.Fprintf(os.Stderr, "Coverage: %.1f%% of %s (%d/%d lines, %d/%d statements)\n",
 				data.Summary.Percent,
+				data.Summary.Metric,
 				data.Summary.CoveredLines,
-				data.Summary.TotalLines)
+				data.Summary.TotalLines,
+				data.Summary.CoveredStatements,
+				data.Summary.TotalStatements)
+			if data.Summary.PartialLines > 0 {
+				fmt.Fprintf(os.Stderr, "Partially covered: %d lines\n", data.Summary.PartialLines)
+			}
+		}
+		if data.Patch != nil {
+			fmt.Fprintf(os.Stderr, "Patch coverage: %.1f%% (%d/%d changed lines)\n",
+				data.Patch.Percent,
+				data.Patch.CoveredLines,
+				data.Patch.TotalLines)
 		}
 	}
 
@@ -143,6 +360,118 @@ func main() {
 	if !noOpen {
 		openBrowser(outputPath)
 	}
+
+	if !reportGates(os.Stderr, data.Gates, quiet) {
+		os.Exit(exitGateFailed)
+	}
+}
+
+// reportGates prints the gates that failed, and the ones that passed unless
+// quiet, and reports whether they all passed.
+func reportGates(w io.Writer, results []model.GateResult, quiet bool) bool {
+	for _, r := range results {
+		switch {
+		case !r.Passed:
+			fmt.Fprintf(w, "Coverage gate -%s failed: %s\n", r.Name, r.Message)
+		case !quiet:
+			fmt.Fprintf(w, "Coverage gate -%s passed: %s\n", r.Name, r.Message)
+		}
+	}
+	return len(gate.Failed(results)) == 0
+}
+
+// readBase reads the base coverage of a diff from a JSON report, which holds
+// its sources, or from a profile with the sources of srcRef when it is set, so
+// that lines are aligned with the sources the base profile was recorded on.
+func readBase(path, srcRoot, srcRef string, opts parser.Options) (*model.CoverageData, error) {
+	if parser.IsJSONReport(path) {
+		return parser.ReadJSON(path, opts.Metric)
+	}
+	if srcRef != "" {
+		sources, err := parser.GitSources(srcRoot, srcRef)
+		if err != nil {
+			return nil, fmt.Errorf("reading base sources: %w", err)
+		}
+		opts.Sources = sources
+	} else {
+		// Files deleted since the base have no source in the current tree
+		opts.KeepMissing = true
+	}
+	return parser.ParseFiles([]string{path}, srcRoot, opts)
+}
+
+func skippedEntries(diagnostics []model.Diagnostic) []model.Diagnostic {
+	var skipped []model.Diagnostic
+	for _, d := range diagnostics {
+		if d.Skipped {
+			skipped = append(skipped, d)
+		}
+	}
+	return skipped
+}
+
+// printDiagnostics describes how each profile entry was resolved, listing
+// every location tried for the entries that were skipped.
+func printDiagnostics(w io.Writer, diagnostics []model.Diagnostic) {
+	for _, d := range diagnostics {
+		if !d.Skipped {
+			fmt.Fprintf(w, "resolved %s -> %s (%s)\n", d.FileName, d.Path, d.Method)
+			continue
+		}
+		fmt.Fprintf(w, "skipped  %s: %s\n", d.FileName, d.Reason)
+		for _, a := range d.Attempts {
+			fmt.Fprintf(w, "         tried %s %s: %s\n", a.Method, a.Path, a.Error)
+		}
+	}
+}
+
+// exportTarget is an export requested with -export format=path.
+type exportTarget struct {
+	format string
+	path   string
+}
+
+func parseExports(specs []string) ([]exportTarget, error) {
+	exports := make([]exportTarget, 0, len(specs))
+	for _, spec := range specs {
+		format, path, found := strings.Cut(spec, "=")
+		if !found || path == "" {
+			return nil, fmt.Errorf("expected format=path, got %q", spec)
+		}
+		if !export.Supported(format) {
+			return nil, fmt.Errorf("unknown format %q, expected one of: %s", format, strings.Join(export.Formats(), ", "))
+		}
+		exports = append(exports, exportTarget{format: format, path: path})
+	}
+	return exports, nil
+}
+
+// expandProfiles expands glob patterns in the -profile arguments. Plain paths
+// are kept as is so that a missing file is reported by the parser.
+func expandProfiles(patterns []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]struct{})
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			matches, err = filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no coverage profiles match %q", pattern)
+			}
+		}
+		for _, m := range matches {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = struct{}{}
+			paths = append(paths, m)
+		}
+	}
+	return paths, nil
 }
 
 func filterByRegex(data *model.CoverageData, patterns []string) (*model.CoverageData, error) {
@@ -179,19 +508,22 @@ func openBrowser(path string) {
 	_ = cmd.Start()
 }
 
-func gitChangedFiles(repoRoot, ref string) (map[string]struct{}, error) {
+// gitChangedLines returns the lines added or modified by a git ref or range
+// in each file changed by it, with paths relative to repoRoot.
+func gitChangedLines(repoRoot, ref string) (map[string][]model.Hunk, error) {
 	rangeSpec := ref
 	if !strings.Contains(ref, "..") {
 		rangeSpec = ref + "^.." + ref
 	}
 
 	//nolint:gosec // G204: rangeSpec is from user input but used safely
-	cmd := exec.Command("git", "-C", repoRoot, "diff", "--name-only", "--diff-filter=ACMR", rangeSpec)
+	cmd := exec.Command("git", "-C", repoRoot, "diff", "-U0", "--no-color", "--no-ext-diff", "--relative", "--diff-filter=ACMR", rangeSpec)
 	output, err := cmd.Output()
 	if err != nil {
 		// Fallback for root commits (no parent)
 		if !strings.Contains(ref, "..") {
-			fallback := exec.Command("git", "-C", repoRoot, "show", "--pretty=", "--name-only", "--diff-filter=ACMR", ref)
+			//nolint:gosec // G204: ref is from user input but used safely
+			fallback := exec.Command("git", "-C", repoRoot, "show", "--pretty=", "-U0", "--no-color", "--no-ext-diff", "--relative", "--diff-filter=ACMR", ref)
 			output, err = fallback.Output()
 			if err != nil {
 				return nil, err
@@ -201,14 +533,52 @@ func gitChangedFiles(repoRoot, ref string) (map[string]struct{}, error) {
 		}
 	}
 
-	files := make(map[string]struct{})
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if line == "" {
-			continue
+	return parser.ParseDiffHunks(bytes.NewReader(output))
+}
+
+// gitRenames returns the files renamed between two git revisions of
+// repoRoot, or between baseRef and the working tree when ref is empty, as a
+// map of new paths to old paths relative to repoRoot.
+func gitRenames(repoRoot, baseRef, ref string) (map[string]string, error) {
+	args := []string{"-C", repoRoot, "diff", "-M", "--name-status", "-z", "--relative", "--diff-filter=R", baseRef}
+	if ref != "" {
+		args = append(args, ref)
+	}
+	output, err := exec.Command("git", args...).Output() //nolint:gosec // G204: refs are from user input but used safely
+	if err != nil {
+		return nil, err
+	}
+
+	// Each rename is "R<score>", the old path and the new path
+	renames := make(map[string]string)
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if strings.HasPrefix(fields[i], "R") {
+			renames[fields[i+2]] = fields[i+1]
 		}
-		files[line] = struct{}{}
 	}
-	return files, nil
+	return renames, nil
+}
+
+// writeFuncTable prints per-function coverage in the same layout as
+// "go tool cover -func", followed by the statement total.
+func writeFuncTable(w io.Writer, data *model.CoverageData) error {
+	tabber := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	var total, covered int
+	for _, file := range data.Files {
+		for _, fn := range file.Functions {
+			fmt.Fprintf(tabber, "%s:%d:\t%s\t%.1f%%\n", file.Path, fn.StartLine, fn.DisplayName(), fn.Percent)
+			total += fn.Statements
+			covered += fn.Covered
+		}
+	}
+
+	percent := 0.0
+	if total > 0 {
+		percent = float64(covered) / float64(total) * 100
+	}
+	fmt.Fprintf(tabber, "total:\t(statements)\t%.1f%%\n", percent)
+	return tabber.Flush()
 }
 
 func parseThresholds(input string) (badge.Thresholds, error) {
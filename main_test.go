@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/chmouel/go-better-html-coverage/internal/model"
@@ -127,3 +131,138 @@ func TestFilterByRegexInvalidPattern(t *testing.T) {
 		t.Errorf("error message should mention invalid regex pattern, got: %v", err)
 	}
 }
+
+func TestWriteFuncTable(t *testing.T) {
+	data := &model.CoverageData{
+		Files: []model.FileData{
+			{
+				ID:   0,
+				Path: "pkg/server.go",
+				Functions: []model.Function{
+					{Name: "Start", Receiver: "*Server", StartLine: 5, Statements: 4, Covered: 3, Percent: 75},
+					{Name: "helper", StartLine: 12, Statements: 4, Covered: 0, Percent: 0},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeFuncTable(&buf, data); err != nil {
+		t.Fatalf("writeFuncTable failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), buf.String())
+	}
+	if !regexp.MustCompile(`^pkg/server\.go:5:\s+\(\*Server\)\.Start\s+75\.0%$`).MatchString(lines[0]) {
+		t.Errorf("unexpected first line %q", lines[0])
+	}
+	if !regexp.MustCompile(`^total:\s+\(statements\)\s+37\.5%$`).MatchString(lines[2]) {
+		t.Errorf("unexpected total line %q", lines[2])
+	}
+}
+
+func TestExpandProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"shard-1.out", "shard-2.out", "unit.out"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("mode: set\n"), 0o644); err != nil { //nolint:gosec // test file
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	paths, err := expandProfiles([]string{
+		filepath.Join(tmpDir, "shard-*.out"),
+		filepath.Join(tmpDir, "unit.out"),
+		filepath.Join(tmpDir, "shard-1.out"),
+	})
+	if err != nil {
+		t.Fatalf("expandProfiles failed: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 unique profiles, got %d: %v", len(paths), paths)
+	}
+	if filepath.Base(paths[0]) != "shard-1.out" || filepath.Base(paths[2]) != "unit.out" {
+		t.Errorf("unexpected order: %v", paths)
+	}
+
+	if _, err := expandProfiles([]string{filepath.Join(tmpDir, "missing-*.out")}); err == nil {
+		t.Error("expected error for glob without matches")
+	}
+}
+
+func TestPrintDiagnostics(t *testing.T) {
+	diagnostics := []model.Diagnostic{
+		{FileName: "example.com/app/a.go", Path: "a.go", Method: "module"},
+		{
+			FileName: "example.com/app/gone.go",
+			Skipped:  true,
+			Reason:   "source file not found",
+			Attempts: []model.ResolveAttempt{{Method: "module", Path: "gone.go", Error: "no such file"}},
+		},
+	}
+
+	skipped := skippedEntries(diagnostics)
+	if len(skipped) != 1 || skipped[0].FileName != "example.com/app/gone.go" {
+		t.Fatalf("unexpected skipped entries: %+v", skipped)
+	}
+
+	var buf bytes.Buffer
+	printDiagnostics(&buf, diagnostics)
+	out := buf.String()
+	for _, want := range []string{
+		"resolved example.com/app/a.go -> a.go (module)",
+		"skipped  example.com/app/gone.go: source file not found",
+		"tried module gone.go: no such file",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseExports(t *testing.T) {
+	exports, err := parseExports([]string{"lcov=coverage.info", "lcov=-"})
+	if err != nil {
+		t.Fatalf("parseExports failed: %v", err)
+	}
+	want := []exportTarget{{format: "lcov", path: "coverage.info"}, {format: "lcov", path: "-"}}
+	if len(exports) != len(want) {
+		t.Fatalf("expected %v, got %v", want, exports)
+	}
+	for i := range want {
+		if exports[i] != want[i] {
+			t.Errorf("export %d: expected %v, got %v", i, want[i], exports[i])
+		}
+	}
+
+	for _, spec := range []string{"lcov", "lcov=", "unknown=out.txt"} {
+		if _, err := parseExports([]string{spec}); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestReportGates(t *testing.T) {
+	results := []model.GateResult{
+		{Name: "min-coverage", Passed: true, Message: "coverage 80.0% meets the minimum of 70.0%"},
+		{Name: "max-regressions", Passed: false, Message: "4 lines lost coverage, more than the 3 allowed"},
+	}
+
+	var buf bytes.Buffer
+	if reportGates(&buf, results, true) {
+		t.Error("expected the gates to fail")
+	}
+	want := "Coverage gate -max-regressions failed: 4 lines lost coverage, more than the 3 allowed\n"
+	if buf.String() != want {
+		t.Errorf("quiet mode should only print failures, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if !reportGates(&buf, results[:1], false) {
+		t.Error("expected the gates to pass")
+	}
+	if !strings.Contains(buf.String(), "Coverage gate -min-coverage passed") {
+		t.Errorf("expected the passed gate to be printed, got %q", buf.String())
+	}
+}